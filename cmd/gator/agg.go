@@ -0,0 +1,542 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+// feedClaimLease is how long a claimed feed is reserved for the worker that
+// claimed it. If that worker's process dies, the feed becomes claimable again
+// once the lease runs out.
+const feedClaimLease = 10 * time.Minute
+
+// feedScrapeTimeout bounds a single scrape, including every request it makes.
+// It is well under feedClaimLease, so a worker stuck on a slow server gives
+// up before its lease runs out and another worker claims the feed.
+const feedScrapeTimeout = 5 * time.Minute
+
+// shutdownGracePeriod is how long in-flight scrapes may keep running after
+// an interrupt before they are cancelled
+const shutdownGracePeriod = 10 * time.Second
+
+// a failing feed is retried after feedBackoffBase, doubling with each
+// consecutive failure up to feedBackoffMax
+const (
+	feedBackoffBase = time.Minute
+	feedBackoffMax  = 24 * time.Hour
+)
+
+// aggOptions configures how an agg session scrapes feeds
+type aggOptions struct {
+	workers int
+	bounds  pollBounds
+	// drain makes each worker keep claiming feeds until none are due
+	drain bool
+	// feedURL and userID, when set, restrict scraping to one feed or to one user's follows
+	feedURL sql.NullString
+	userID  uuid.NullUUID
+}
+
+// scrapeResult records the outcome of scraping a single feed
+type scrapeResult struct {
+	Feed        database.Feed
+	PostsSaved  int
+	NotModified bool
+	Err         error
+}
+
+// aggSummary totals the results of every scrape in an agg session
+type aggSummary struct {
+	feeds       int
+	notModified int
+	failed      int
+	posts       int
+}
+
+func (a *aggSummary) add(results []scrapeResult) {
+	for _, result := range results {
+		a.feeds++
+		a.posts += result.PostsSaved
+		if result.NotModified {
+			a.notModified++
+		}
+		if result.Err != nil {
+			a.failed++
+		}
+	}
+}
+
+func (a *aggSummary) print(elapsed time.Duration) {
+	fmt.Printf("Scraping stopped after %s\n", elapsed.Round(time.Second))
+	fmt.Printf("Feeds scraped: %d (%d not modified, %d failed)\n", a.feeds, a.notModified, a.failed)
+	fmt.Printf("Posts saved: %d\n", a.posts)
+}
+
+func handlerAgg(s *state, cmd command) error {
+	fs := flag.NewFlagSet("agg", flag.ContinueOnError)
+	workers := fs.Int("workers", 1, "number of feeds to scrape concurrently")
+	minInterval := fs.Duration("min-interval", 15*time.Minute, "shortest time between polls of one feed")
+	maxInterval := fs.Duration("max-interval", 24*time.Hour, "longest time between polls of one feed")
+	once := fs.Bool("once", false, "scrape every due feed once and exit")
+	feedURL := fs.String("feed", "", "only scrape the feed at this url, even if it isn't due")
+	mine := fs.Bool("mine", false, "only scrape feeds the current user follows")
+
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if *once && len(args) != 0 {
+		return errors.New("expected no arguments with --once")
+	}
+	if !*once && len(args) != 1 {
+		return errors.New("expected 1 arguments <string:timeBetweenRequests>")
+	}
+	if *workers < 1 {
+		return errors.New("invalid workers argument: expected at least 1")
+	}
+	if *minInterval > *maxInterval {
+		return errors.New("invalid interval arguments: min-interval is longer than max-interval")
+	}
+
+	opts := aggOptions{
+		workers: *workers,
+		bounds:  pollBounds{min: *minInterval, max: *maxInterval},
+		drain:   *once,
+		feedURL: sql.NullString{String: *feedURL, Valid: *feedURL != ""},
+	}
+
+	if *mine {
+		user, err := s.db.GetUserByName(context.Background(), s.cfg.CurrentUserName)
+		if err != nil {
+			return err
+		}
+		opts.userID = uuid.NullUUID{UUID: user.ID, Valid: true}
+	}
+
+	// stop claiming feeds on the first interrupt, then give in-flight scrapes
+	// a grace period before cancelling them. A second interrupt exits immediately.
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopShutdown := context.AfterFunc(sigCtx, func() {
+		stop()
+		fmt.Println("Shutting down, waiting for in-flight scrapes to finish...")
+		time.AfterFunc(shutdownGracePeriod, cancel)
+	})
+	// deferred after stop so it runs first, otherwise the stop on a normal
+	// return would cancel sigCtx and announce a shutdown
+	defer stopShutdown()
+
+	if *once {
+		return aggOnce(ctx, sigCtx.Done(), s, opts)
+	}
+
+	timeBetweenRequests := args[0]
+	t, err := time.ParseDuration(timeBetweenRequests)
+	if err != nil {
+		return err
+	}
+
+	var summary aggSummary
+	start := time.Now()
+
+	ticker := time.NewTicker(t)
+	defer ticker.Stop()
+	fmt.Println("Press ctrl+C to cancel scraping")
+	fmt.Printf("Scraping with %d worker(s)...\n", *workers)
+	for {
+		summary.add(scrapeFeeds(ctx, sigCtx.Done(), s, opts))
+
+		select {
+		case <-sigCtx.Done():
+			summary.print(time.Since(start))
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// aggOnce scrapes every due feed a single time, for running from cron or a
+// systemd timer, and fails if any feed could not be scraped
+func aggOnce(ctx context.Context, stopping <-chan struct{}, s *state, opts aggOptions) error {
+	results := scrapeFeeds(ctx, stopping, s, opts)
+	if len(results) == 0 {
+		fmt.Println("No feeds are due")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FEED\tSTATUS\tNEW POSTS\tERROR")
+	failed := 0
+	for _, result := range results {
+		status := "ok"
+		errMsg := ""
+		switch {
+		case result.Err != nil:
+			status = "failed"
+			errMsg = result.Err.Error()
+			failed++
+		case result.NotModified:
+			status = "not modified"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", result.Feed.Name, status, result.PostsSaved, errMsg)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d feeds failed", failed, len(results))
+	}
+
+	return nil
+}
+
+// scrapeFeeds runs a pool of workers that each claim and scrape the next due
+// feed, or with opts.drain keep claiming until no feed is due. A failing feed
+// is logged without affecting the other workers. Workers stop claiming once
+// stopping is closed.
+func scrapeFeeds(ctx context.Context, stopping <-chan struct{}, s *state, opts aggOptions) []scrapeResult {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results []scrapeResult
+		seen    = make(map[uuid.UUID]bool)
+	)
+
+	// scrapeOne returns false when the worker should stop claiming feeds
+	scrapeOne := func() bool {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("scrape worker panicked: %v", r)
+			}
+		}()
+
+		select {
+		case <-stopping:
+			return false
+		default:
+		}
+
+		nextFeed, err := claimNextFeed(ctx, s, opts)
+		if errors.Is(err, sql.ErrNoRows) {
+			// no feed is due, or every due feed is claimed by another worker
+			return false
+		}
+		if err != nil {
+			log.Println(err)
+			return false
+		}
+
+		// a feed whose next fetch could not be scheduled stays due; don't scrape it twice
+		mu.Lock()
+		repeat := seen[nextFeed.ID]
+		seen[nextFeed.ID] = true
+		mu.Unlock()
+		if repeat {
+			if err := releaseFeedClaim(ctx, s, nextFeed); err != nil {
+				log.Printf("releasing claim on %s: %v", nextFeed.Name, err)
+			}
+			return false
+		}
+
+		result := scrapeClaimedFeed(ctx, s, nextFeed, opts.bounds)
+		if result.Err != nil {
+			log.Printf("scraping %s: %v", nextFeed.Name, result.Err)
+		}
+
+		mu.Lock()
+		results = append(results, result)
+		mu.Unlock()
+
+		return true
+	}
+
+	for i := 0; i < opts.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for scrapeOne() {
+				if !opts.drain {
+					break
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// claimNextFeed atomically picks the most overdue unclaimed feed that is not
+// backing off after failures, or the feed opts.feedURL names whenever it's
+// unclaimed, marks it fetched and leases it, so concurrent workers and other
+// gator processes sharing the database never scrape the same feed at once
+func claimNextFeed(ctx context.Context, s *state, opts aggOptions) (database.Feed, error) {
+	now := time.Now()
+
+	claimParams := database.ClaimNextFeedParams{
+		Now:          now,
+		ClaimedUntil: sql.NullTime{Time: now.Add(feedClaimLease), Valid: true},
+		Url:          opts.feedURL,
+		UserID:       opts.userID,
+	}
+
+	return s.db.ClaimNextFeed(ctx, claimParams)
+}
+
+// scrapeClaimedFeed scrapes a feed claimed by claimNextFeed and releases the claim
+func scrapeClaimedFeed(ctx context.Context, s *state, feed database.Feed, bounds pollBounds) scrapeResult {
+	result := scrapeResult{Feed: feed}
+
+	scrapeCtx, cancelScrape := context.WithTimeout(ctx, feedScrapeTimeout)
+	defer cancelScrape()
+
+	var hints scheduleHints
+	result.PostsSaved, hints, result.Err = scrapeFeed(scrapeCtx, s, feed)
+	if errors.Is(result.Err, errNotModified) {
+		result.NotModified = true
+		result.Err = nil
+	}
+
+	// finish bookkeeping even when the scrape was cancelled by a shutdown
+	releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	// an interrupted scrape says nothing about the feed's health
+	if !errors.Is(result.Err, context.Canceled) {
+		if err := recordFeedHealth(releaseCtx, s, feed, result.Err); err != nil {
+			log.Printf("recording health of %s: %v", feed.Name, err)
+		}
+	}
+
+	// failing feeds are rescheduled by their backoff instead
+	if result.Err == nil {
+		if err := scheduleNextFetch(releaseCtx, s, feed, hints, bounds); err != nil {
+			log.Printf("scheduling next fetch of %s: %v", feed.Name, err)
+		}
+	}
+
+	// release the claim even when scraping failed, so the feed rejoins the rotation
+	if err := releaseFeedClaim(releaseCtx, s, feed); err != nil {
+		log.Printf("releasing claim on %s: %v", feed.Name, err)
+	}
+
+	return result
+}
+
+// releaseFeedClaim gives up the claim claimNextFeed took on a feed. It does
+// nothing if the lease ran out and another worker has claimed the feed since.
+func releaseFeedClaim(ctx context.Context, s *state, feed database.Feed) error {
+	return s.db.ReleaseFeedClaim(ctx, database.ReleaseFeedClaimParams{
+		ID:           feed.ID,
+		ClaimedUntil: feed.ClaimedUntil,
+	})
+}
+
+// recordFeedHealth resets a feed's failure count after a successful scrape,
+// or counts the failure and backs the feed off exponentially. Like the other
+// bookkeeping it only applies while the worker still holds feed's claim.
+func recordFeedHealth(ctx context.Context, s *state, feed database.Feed, scrapeErr error) error {
+	now := time.Now()
+
+	if scrapeErr == nil {
+		return s.db.MarkFeedSucceeded(ctx, database.MarkFeedSucceededParams{
+			ID:              feed.ID,
+			LastSucceededAt: sql.NullTime{Time: now, Valid: true},
+			ClaimedUntil:    feed.ClaimedUntil,
+		})
+	}
+
+	backoff := feedBackoff(feed.ConsecutiveFailures + 1)
+	return s.db.MarkFeedFailed(ctx, database.MarkFeedFailedParams{
+		ID:           feed.ID,
+		LastError:    sql.NullString{String: scrapeErr.Error(), Valid: true},
+		BackoffUntil: sql.NullTime{Time: now.Add(backoff), Valid: true},
+		ClaimedUntil: feed.ClaimedUntil,
+	})
+}
+
+func feedBackoff(failures int32) time.Duration {
+	backoff := feedBackoffBase
+	for i := int32(1); i < failures && backoff < feedBackoffMax; i++ {
+		backoff *= 2
+	}
+	return min(backoff, feedBackoffMax)
+}
+
+// scrapeFeed fetches a feed and saves its new posts, returning how many were
+// saved along with the channel's polling hints
+func scrapeFeed(ctx context.Context, s *state, nextFeed database.Feed) (int, scheduleHints, error) {
+	cache := cacheHeaders{
+		ETag:         nextFeed.Etag.String,
+		LastModified: nextFeed.LastModified.String,
+	}
+
+	fetchedAt := time.Now()
+	rss, newCache, err := fetchFeed(ctx, nextFeed.Url, cache)
+	if err != nil {
+		return 0, scheduleHints{}, err
+	}
+	hints := hintsFromFeed(rss)
+
+	err = adoptLegacyPostGUIDs(ctx, s, nextFeed, rss.Channel.Item)
+	if err != nil {
+		return 0, hints, err
+	}
+
+	saved := 0
+
+	for _, item := range rss.Channel.Item {
+
+		guid := item.identity()
+		if guid == "" {
+			log.Printf("skipping item with no guid, link or title in %s", nextFeed.Name)
+			continue
+		}
+
+		// keep items with missing or unreadable dates, stamped with the fetch time instead
+		publishedAt, err := parsePubDate(item.PubDate)
+		publishedAtInferred := err != nil
+		if publishedAtInferred {
+			publishedAt = fetchedAt.UTC()
+		}
+
+		postParams := database.CreatePostParams{
+			CreatedAt:           time.Now(),
+			UpdatedAt:           time.Now(),
+			Title:               item.Title,
+			Url:                 item.Link,
+			Description:         item.Description,
+			PublishedAt:         publishedAt,
+			FeedID:              nextFeed.ID,
+			PublishedAtInferred: publishedAtInferred,
+			Guid:                guid,
+			Content:             item.Content,
+			Author:              item.authorName(),
+		}
+		// a post is saved along with its categories and enclosures or not at all,
+		// so a failure part way doesn't leave it to be skipped as saved next time
+		var post database.Post
+		err = s.db.InTx(ctx, func(db database.Querier) error {
+			post, err = db.CreatePost(ctx, postParams)
+			if err != nil {
+				return err
+			}
+			err = savePostCategories(ctx, db, post.ID, item.categoryNames())
+			if err != nil {
+				return err
+			}
+			return savePostEnclosures(ctx, db, post.ID, item)
+		})
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return saved, hints, err
+		}
+		saved++
+		fmt.Printf("Saved post %s from %s for user %s\n", post.Title, nextFeed.Name, s.cfg.CurrentUserName)
+
+	}
+
+	// only remember the validators once every post is saved, so a failed run is retried in full
+	cacheParams := database.UpdateFeedCacheHeadersParams{
+		ID:           nextFeed.ID,
+		Etag:         sql.NullString{String: newCache.ETag, Valid: newCache.ETag != ""},
+		LastModified: sql.NullString{String: newCache.LastModified, Valid: newCache.LastModified != ""},
+	}
+
+	err = s.db.UpdateFeedCacheHeaders(ctx, cacheParams)
+	if err != nil {
+		return saved, hints, err
+	}
+
+	return saved, hints, nil
+
+}
+
+// adoptLegacyPostGUIDs gives posts saved before guids were tracked, which had
+// their url copied into the guid column, the guids of the items they came
+// from, so they aren't saved a second time. Only the first scrape of each feed
+// migration 008 flagged has work to do; for every other it's one DELETE.
+func adoptLegacyPostGUIDs(ctx context.Context, s *state, feed database.Feed, items []RSSItem) error {
+	return s.db.InTx(ctx, func(db database.Querier) error {
+		// the flag is only cleared if every post is updated
+		flagged, err := db.DeleteLegacyGUIDFeed(ctx, feed.ID)
+		if err != nil || flagged == 0 {
+			return err
+		}
+		for _, item := range items {
+			guid := item.identity()
+			if item.Link == "" || guid == "" || guid == item.Link {
+				continue
+			}
+			_, err := db.UpdateLegacyPostGUID(ctx, database.UpdateLegacyPostGUIDParams{
+				Guid:   guid,
+				FeedID: feed.ID,
+				Url:    item.Link,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// savePostCategories tags a post with each named category, creating the
+// categories that don't exist yet
+func savePostCategories(ctx context.Context, db database.Querier, postID int32, names []string) error {
+	for _, name := range names {
+		category, err := db.CreateCategory(ctx, name)
+		if err != nil {
+			return err
+		}
+		err = db.AddPostCategory(ctx, database.AddPostCategoryParams{
+			PostID:     postID,
+			CategoryID: category.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// savePostEnclosures stores the media files attached to an item. The item's
+// itunes:duration applies to each of them.
+func savePostEnclosures(ctx context.Context, db database.Querier, postID int32, item RSSItem) error {
+	for _, enclosure := range item.Enclosures {
+		url := strings.TrimSpace(enclosure.URL)
+		if url == "" {
+			continue
+		}
+		err := db.CreateEnclosure(ctx, database.CreateEnclosureParams{
+			PostID:          postID,
+			Url:             url,
+			MimeType:        strings.TrimSpace(enclosure.Type),
+			SizeBytes:       enclosure.sizeBytes(),
+			DurationSeconds: item.durationSeconds(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
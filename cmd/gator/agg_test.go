@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/config"
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/Breadumi/aggreGator/internal/memdb"
+	"github.com/google/uuid"
+)
+
+// newTestState returns a state backed by an empty in-memory database. HOME
+// points at a temporary directory so logging in doesn't touch the real config.
+func newTestState(t *testing.T) *state {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	return &state{db: memdb.New(), cfg: &config.Config{}}
+}
+
+// run calls a command handler, failing the test if it returns an error
+func run(t *testing.T, s *state, handler func(*state, command) error, name string, args ...string) {
+	t.Helper()
+	if err := handler(s, command{name: name, args: args}); err != nil {
+		t.Fatalf("%s %s: %v", name, strings.Join(args, " "), err)
+	}
+}
+
+// feedServer serves an RSS feed whose items can be changed between scrapes
+type feedServer struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	items []string
+}
+
+func newFeedServer(t *testing.T, items ...string) *feedServer {
+	t.Helper()
+	fs := &feedServer{items: items}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Example</title>`)
+		for _, item := range fs.items {
+			fmt.Fprint(w, item)
+		}
+		fmt.Fprint(w, `</channel></rss>`)
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+func (fs *feedServer) setItems(items ...string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.items = items
+}
+
+func rssItem(guid, title string) string {
+	return fmt.Sprintf(`<item><guid>%s</guid><title>%s</title><link>https://example.com/%s</link>`+
+		`<pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>`, guid, title, guid)
+}
+
+var testAggOptions = aggOptions{
+	workers: 1,
+	drain:   true,
+	bounds:  pollBounds{min: time.Minute, max: time.Hour},
+}
+
+func postsForUser(t *testing.T, s *state, name string) []database.GetPostsByUserRow {
+	t.Helper()
+	ctx := context.Background()
+	user, err := s.db.GetUserByName(ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts, err := s.db.GetPostsByUser(ctx, database.GetPostsByUserParams{UserID: user.ID, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return posts
+}
+
+func TestScrapeFeedsSkipsSavedItems(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	feed := newFeedServer(t, rssItem("a", "First"), rssItem("b", "Second"))
+
+	run(t, s, handlerRegister, "register", "alice")
+	run(t, s, middlewareLoggedIn(handlerAddFeed), "addfeed", "Example", feed.URL)
+
+	results := scrapeFeeds(ctx, nil, s, testAggOptions)
+	if len(results) != 1 || results[0].Err != nil || results[0].PostsSaved != 2 {
+		t.Fatalf("first scrape: got %+v, want one feed with 2 posts saved", results)
+	}
+
+	// the feed was scheduled into the future, so a second session finds nothing due
+	if results := scrapeFeeds(ctx, nil, s, testAggOptions); len(results) != 0 {
+		t.Fatalf("second scrape: got %+v, want no feeds due", results)
+	}
+
+	feed.setItems(rssItem("c", "Third"), rssItem("a", "First again"), rssItem("b", "Second"))
+	dbFeed, err := s.db.GetFeedByURL(ctx, feed.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, _, err := scrapeFeed(ctx, s, dbFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved != 1 {
+		t.Errorf("rescrape saved %d posts, want 1", saved)
+	}
+
+	posts := postsForUser(t, s, "alice")
+	if len(posts) != 3 {
+		t.Fatalf("got %d posts, want 3", len(posts))
+	}
+	for _, post := range posts {
+		if post.Guid == "a" && post.Title != "First" {
+			t.Errorf("saved post was overwritten: got title %q", post.Title)
+		}
+	}
+}
+
+// claimFeed claims the feed with the given url, failing the test if it isn't due
+func claimFeed(t *testing.T, s *state, url string) database.Feed {
+	t.Helper()
+	feed, err := claimNextFeed(context.Background(), s, aggOptions{feedURL: sql.NullString{String: url, Valid: true}})
+	if err != nil {
+		t.Fatalf("claiming %s: %v", url, err)
+	}
+	return feed
+}
+
+func releaseTestClaim(t *testing.T, s *state, feed database.Feed) {
+	t.Helper()
+	if err := releaseFeedClaim(context.Background(), s, feed); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClaimNextFeedOrder(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	run(t, s, handlerRegister, "register", "alice")
+	user, err := s.db.GetUserByName(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	feeds := make(map[string]uuid.UUID)
+	for _, name := range []string{"unscheduled", "due", "overdue", "later", "backing-off"} {
+		feed, err := s.db.CreateFeed(ctx, database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Name:      name,
+			Url:       "https://example.com/" + name,
+			UserID:    user.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		feeds[name] = feed.ID
+	}
+
+	nextFetch := map[string]time.Time{
+		"due":     now.Add(-time.Hour),
+		"overdue": now.Add(-2 * time.Hour),
+		"later":   now.Add(time.Hour),
+	}
+	for name, at := range nextFetch {
+		feed := claimFeed(t, s, "https://example.com/"+name)
+		err := s.db.SetFeedNextFetch(ctx, database.SetFeedNextFetchParams{
+			ID:           feed.ID,
+			NextFetchAt:  sql.NullTime{Time: at, Valid: true},
+			ClaimedUntil: feed.ClaimedUntil,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		releaseTestClaim(t, s, feed)
+	}
+	failing := claimFeed(t, s, "https://example.com/backing-off")
+	err = recordFeedHealth(ctx, s, failing, errors.New("boom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseTestClaim(t, s, failing)
+
+	// feeds never scheduled come first, then the most overdue; claimed,
+	// future and backed off feeds are skipped
+	for _, want := range []string{"unscheduled", "overdue", "due"} {
+		feed, err := claimNextFeed(ctx, s, aggOptions{})
+		if err != nil {
+			t.Fatalf("claiming %s: %v", want, err)
+		}
+		if feed.Name != want {
+			t.Fatalf("claimed %s, want %s", feed.Name, want)
+		}
+	}
+	if feed, err := claimNextFeed(ctx, s, aggOptions{}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("claimed %s with every due feed claimed, want sql.ErrNoRows", feed.Name)
+	}
+
+	// a released feed that is still due can be claimed again
+	due, err := s.db.GetFeedByID(ctx, feeds["due"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseTestClaim(t, s, due)
+	feed, err := claimNextFeed(ctx, s, aggOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Name != "due" {
+		t.Errorf("claimed %s after release, want due", feed.Name)
+	}
+}
+
+func TestScrapeFeedsByURLIgnoresSchedule(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	feed := newFeedServer(t, rssItem("a", "First"))
+
+	run(t, s, handlerRegister, "register", "alice")
+	run(t, s, middlewareLoggedIn(handlerAddFeed), "addfeed", "Example", feed.URL)
+
+	if results := scrapeFeeds(ctx, nil, s, testAggOptions); len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("first scrape: got %+v", results)
+	}
+	if results := scrapeFeeds(ctx, nil, s, testAggOptions); len(results) != 0 {
+		t.Fatalf("scraped %d feeds before they were due, want 0", len(results))
+	}
+
+	// asking for the feed fetches it now, once, even in drain mode
+	opts := testAggOptions
+	opts.feedURL = sql.NullString{String: feed.URL, Valid: true}
+	feed.setItems(rssItem("a", "First"), rssItem("b", "Second"))
+	results := scrapeFeeds(ctx, nil, s, opts)
+	if len(results) != 1 || results[0].Err != nil || results[0].PostsSaved != 1 {
+		t.Fatalf("scrape by url: got %+v, want the feed with 1 new post", results)
+	}
+}
+
+func TestScrapeFeedAdoptsGUIDOfLegacyPost(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	feed := newFeedServer(t, rssItem("a", "First"))
+
+	run(t, s, handlerRegister, "register", "alice")
+	run(t, s, middlewareLoggedIn(handlerAddFeed), "addfeed", "Example", feed.URL)
+	dbFeed, err := s.db.GetFeedByURL(ctx, feed.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// posts saved before guids were tracked had their url backfilled as the
+	// guid, and migration 008 flagged their feed
+	now := time.Now()
+	_, err = s.db.CreatePost(ctx, database.CreatePostParams{
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Title:       "First",
+		Url:         "https://example.com/a",
+		PublishedAt: now,
+		FeedID:      dbFeed.ID,
+		Guid:        "https://example.com/a",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.db.(*memdb.Queries).AddLegacyGUIDFeed(dbFeed.ID)
+
+	saved, _, err := scrapeFeed(ctx, s, dbFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved != 0 {
+		t.Errorf("saved %d posts for an item already stored under its url, want 0", saved)
+	}
+
+	posts := postsForUser(t, s, "alice")
+	if len(posts) != 1 {
+		t.Fatalf("got %d posts, want 1", len(posts))
+	}
+	if posts[0].Guid != "a" {
+		t.Errorf("legacy post guid is %q, want a", posts[0].Guid)
+	}
+
+	// the flag is cleared once the guids are adopted
+	if flagged, err := s.db.DeleteLegacyGUIDFeed(ctx, dbFeed.ID); err != nil || flagged != 0 {
+		t.Errorf("feed still flagged after scraping: %d rows, err = %v", flagged, err)
+	}
+}
+
+// failingEnclosures is a store whose enclosures can't be saved
+type failingEnclosures struct {
+	*memdb.Queries
+}
+
+func (f failingEnclosures) CreateEnclosure(ctx context.Context, arg database.CreateEnclosureParams) error {
+	return errors.New("enclosures unavailable")
+}
+
+func (f failingEnclosures) InTx(ctx context.Context, fn func(database.Querier) error) error {
+	return f.Queries.InTx(ctx, func(database.Querier) error { return fn(f) })
+}
+
+func TestScrapeFeedSavesPostsWithTheirEnclosuresOrNotAtAll(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	feed := newFeedServer(t, `<item><guid>episode</guid><title>Episode</title><category>Podcasts</category>`+
+		`<enclosure url="https://example.com/episode.mp3" type="audio/mpeg" length="1000"/></item>`)
+
+	run(t, s, handlerRegister, "register", "alice")
+	run(t, s, middlewareLoggedIn(handlerAddFeed), "addfeed", "Example", feed.URL)
+	dbFeed, err := s.db.GetFeedByURL(ctx, feed.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := s.db.(*memdb.Queries)
+	s.db = failingEnclosures{db}
+	if _, _, err := scrapeFeed(ctx, s, dbFeed); err == nil {
+		t.Fatal("scrape succeeded without saving the enclosure")
+	}
+	if posts := postsForUser(t, s, "alice"); len(posts) != 0 {
+		t.Fatalf("got %d posts after the enclosure failed, want 0", len(posts))
+	}
+
+	// the next scrape saves the post rather than finding it already there
+	s.db = db
+	saved, _, err := scrapeFeed(ctx, s, dbFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved != 1 {
+		t.Errorf("saved %d posts on retry, want 1", saved)
+	}
+	posts := postsForUser(t, s, "alice")
+	if len(posts) != 1 {
+		t.Fatalf("got %d posts, want 1", len(posts))
+	}
+	alice, err := s.db.GetUserByName(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enclosures, err := s.db.GetEnclosuresByUser(ctx, database.GetEnclosuresByUserParams{UserID: alice.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enclosures) != 1 {
+		t.Errorf("got %d enclosures, want 1", len(enclosures))
+	}
+}
+
+func TestExpiredClaimDoesNotOverwriteNewOwner(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	feed := newFeedServer(t)
+
+	run(t, s, handlerRegister, "register", "alice")
+	run(t, s, middlewareLoggedIn(handlerAddFeed), "addfeed", "Example", feed.URL)
+	stale := claimFeed(t, s, feed.URL)
+
+	// another worker claims the feed once the first worker's lease runs out
+	later := time.Now().Add(feedClaimLease + time.Minute)
+	owner, err := s.db.ClaimNextFeed(ctx, database.ClaimNextFeedParams{
+		Now:          later,
+		ClaimedUntil: sql.NullTime{Time: later.Add(feedClaimLease), Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first worker finishes late and fails
+	if err := recordFeedHealth(ctx, s, stale, errors.New("timed out")); err != nil {
+		t.Fatal(err)
+	}
+	if err := scheduleNextFetch(ctx, s, stale, scheduleHints{}, testAggOptions.bounds); err != nil {
+		t.Fatal(err)
+	}
+	releaseTestClaim(t, s, stale)
+
+	got, err := s.db.GetFeedByID(ctx, owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.ClaimedUntil.Valid || !got.ClaimedUntil.Time.Equal(owner.ClaimedUntil.Time) {
+		t.Errorf("expired worker changed the claim to %v, want %v", got.ClaimedUntil, owner.ClaimedUntil)
+	}
+	if got.ConsecutiveFailures != 0 || got.BackoffUntil.Valid || got.NextFetchAt.Valid {
+		t.Errorf("expired worker updated the feed: %+v", got)
+	}
+
+	releaseTestClaim(t, s, owner)
+	got, err = s.db.GetFeedByID(ctx, owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ClaimedUntil.Valid {
+		t.Errorf("owner's release left the claim until %v", got.ClaimedUntil.Time)
+	}
+}
@@ -0,0 +1,510 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+// default and maximum number of posts returned by GET /v1/posts
+const (
+	apiDefaultPostLimit = 20
+	apiMaxPostLimit     = 100
+)
+
+type apiUser struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+}
+
+type apiFeed struct {
+	ID                  uuid.UUID  `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	Name                string     `json:"name"`
+	URL                 string     `json:"url"`
+	UserID              uuid.UUID  `json:"user_id"`
+	LastFetchedAt       *time.Time `json:"last_fetched_at"`
+	LastSucceededAt     *time.Time `json:"last_succeeded_at"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	LastError           *string    `json:"last_error"`
+}
+
+type apiFeedFollow struct {
+	ID          int32     `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	FeedID      uuid.UUID `json:"feed_id"`
+	FeedName    string    `json:"feed_name"`
+	FeedURL     string    `json:"feed_url"`
+	Folder      string    `json:"folder"`
+	UnreadCount int64     `json:"unread_count"`
+}
+
+type apiPost struct {
+	ID                  int32     `json:"id"`
+	Title               string    `json:"title"`
+	URL                 string    `json:"url"`
+	Description         string    `json:"description"`
+	Author              string    `json:"author"`
+	PublishedAt         time.Time `json:"published_at"`
+	PublishedAtInferred bool      `json:"published_at_inferred"`
+	FeedID              uuid.UUID `json:"feed_id"`
+	FeedName            string    `json:"feed_name"`
+}
+
+// apiPostDetail adds the fields only returned for a single post
+type apiPostDetail struct {
+	apiPost
+	Content    string   `json:"content"`
+	Categories []string `json:"categories"`
+}
+
+func toAPIUser(user database.User) apiUser {
+	return apiUser{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		Name:      user.Name,
+	}
+}
+
+func toAPIFeed(feed database.Feed) apiFeed {
+	apiFeed := apiFeed{
+		ID:                  feed.ID,
+		CreatedAt:           feed.CreatedAt,
+		UpdatedAt:           feed.UpdatedAt,
+		Name:                feed.Name,
+		URL:                 feed.Url,
+		UserID:              feed.UserID,
+		ConsecutiveFailures: feed.ConsecutiveFailures,
+	}
+	if feed.LastFetchedAt.Valid {
+		apiFeed.LastFetchedAt = &feed.LastFetchedAt.Time
+	}
+	if feed.LastSucceededAt.Valid {
+		apiFeed.LastSucceededAt = &feed.LastSucceededAt.Time
+	}
+	if feed.LastError.Valid {
+		apiFeed.LastError = &feed.LastError.String
+	}
+	return apiFeed
+}
+
+func apiGetUsers(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	users, err := s.db.GetUsers(r.Context())
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	resp := make([]apiUser, 0, len(users))
+	for _, user := range users {
+		resp = append(resp, toAPIUser(user))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func apiGetCurrentUser(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	respondWithJSON(w, http.StatusOK, toAPIUser(user))
+}
+
+func apiGetFeeds(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	feeds, err := s.db.GetFeeds(r.Context())
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	resp := make([]apiFeed, 0, len(feeds))
+	for _, feed := range feeds {
+		resp = append(resp, toAPIFeed(feed))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// apiCreateFeed adds a new feed and follows it for the user, like addfeed
+func apiCreateFeed(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	var params struct {
+		Name   string `json:"name"`
+		URL    string `json:"url"`
+		Folder string `json:"folder"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if params.Name == "" || params.URL == "" {
+		respondWithError(w, http.StatusBadRequest, "name and url are required")
+		return
+	}
+	if err := checkFolder(params.Folder); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	_, err := s.db.GetFeedByURL(r.Context(), params.URL)
+	if err == nil {
+		respondWithError(w, http.StatusConflict, "a feed with this url already exists")
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		respondWithServerError(w, err)
+		return
+	}
+
+	feed, err := s.db.CreateFeed(r.Context(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      params.Name,
+		Url:       params.URL,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	follow, err := s.db.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+		Folder:    params.Folder,
+	})
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, struct {
+		Feed       apiFeed       `json:"feed"`
+		FeedFollow apiFeedFollow `json:"feed_follow"`
+	}{
+		Feed: toAPIFeed(feed),
+		FeedFollow: apiFeedFollow{
+			ID:        follow.ID,
+			CreatedAt: follow.CreatedAt,
+			FeedID:    follow.FeedID,
+			FeedName:  follow.FeedName,
+			FeedURL:   feed.Url,
+			Folder:    follow.Folder,
+		},
+	})
+}
+
+func apiGetFeedFollows(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := s.db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	resp := make([]apiFeedFollow, 0, len(follows))
+	for _, follow := range follows {
+		resp = append(resp, apiFeedFollow{
+			ID:          follow.ID,
+			CreatedAt:   follow.CreatedAt,
+			FeedID:      follow.FeedID,
+			FeedName:    follow.FeedName,
+			FeedURL:     follow.FeedUrl,
+			Folder:      follow.Folder,
+			UnreadCount: follow.UnreadCount,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func apiCreateFeedFollow(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	var params struct {
+		FeedURL string `json:"feed_url"`
+		Folder  string `json:"folder"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := checkFolder(params.Folder); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	feed, err := s.db.GetFeedByURL(r.Context(), params.FeedURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "no feed with this url exists")
+		return
+	}
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	follows, err := s.db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+	for _, follow := range follows {
+		if follow.FeedID == feed.ID {
+			respondWithError(w, http.StatusConflict, "already following this feed")
+			return
+		}
+	}
+
+	follow, err := s.db.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+		Folder:    params.Folder,
+	})
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, apiFeedFollow{
+		ID:        follow.ID,
+		CreatedAt: follow.CreatedAt,
+		FeedID:    follow.FeedID,
+		FeedName:  follow.FeedName,
+		FeedURL:   feed.Url,
+		Folder:    follow.Folder,
+	})
+}
+
+// apiDeleteFeedFollow unfollows the feed given by the url query parameter
+func apiDeleteFeedFollow(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	feedURL := r.URL.Query().Get("url")
+	if feedURL == "" {
+		respondWithError(w, http.StatusBadRequest, "url query parameter is required")
+		return
+	}
+
+	err := s.db.DeleteFeedFollow(r.Context(), database.DeleteFeedFollowParams{
+		UserID: user.ID,
+		Url:    feedURL,
+	})
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiGetPosts lists posts from the user's followed feeds, newest first. It
+// accepts the same filters as browse as query parameters: limit, offset,
+// since, until, feed, category, author and unread.
+func apiGetPosts(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	query := r.URL.Query()
+
+	limit, err := queryInt(query.Get("limit"), apiDefaultPostLimit)
+	if err != nil || limit < 1 || limit > apiMaxPostLimit {
+		respondWithError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(apiMaxPostLimit))
+		return
+	}
+	offset, err := queryInt(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		respondWithError(w, http.StatusBadRequest, "offset must be a positive integer")
+		return
+	}
+
+	params := database.GetPostsByUserParams{
+		UserID:     user.ID,
+		FeedUrl:    sql.NullString{String: query.Get("feed"), Valid: query.Get("feed") != ""},
+		Category:   sql.NullString{String: query.Get("category"), Valid: query.Get("category") != ""},
+		Author:     sql.NullString{String: query.Get("author"), Valid: query.Get("author") != ""},
+		UnreadOnly: query.Get("unread") == "true",
+		Limit:      int32(limit),
+		Offset:     int32(offset),
+	}
+	if params.Since, err = parseDateFlag("since", query.Get("since")); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if params.Until, err = parseDateFlag("until", query.Get("until")); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	posts, err := s.db.GetPostsByUser(r.Context(), params)
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	resp := make([]apiPost, 0, len(posts))
+	for _, post := range posts {
+		resp = append(resp, apiPost{
+			ID:                  post.ID,
+			Title:               post.Title,
+			URL:                 post.Url,
+			Description:         post.Description,
+			Author:              post.Author,
+			PublishedAt:         post.PublishedAt,
+			PublishedAtInferred: post.PublishedAtInferred,
+			FeedID:              post.FeedID,
+			FeedName:            post.FeedName,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// apiGetPost returns a single post with its full content and categories
+func apiGetPost(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := strconv.ParseInt(r.PathValue("postID"), 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid post id")
+		return
+	}
+
+	post, err := s.db.GetPostByID(r.Context(), int32(postID))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "no post with this id exists")
+		return
+	}
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	feed, err := s.db.GetFeedByID(r.Context(), post.FeedID)
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	categories, err := s.db.GetCategoriesForPost(r.Context(), post.ID)
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	resp := apiPostDetail{
+		apiPost: apiPost{
+			ID:                  post.ID,
+			Title:               post.Title,
+			URL:                 post.Url,
+			Description:         post.Description,
+			Author:              post.Author,
+			PublishedAt:         post.PublishedAt,
+			PublishedAtInferred: post.PublishedAtInferred,
+			FeedID:              post.FeedID,
+			FeedName:            feed.Name,
+		},
+		Content:    post.Content,
+		Categories: make([]string, 0, len(categories)),
+	}
+	for _, category := range categories {
+		resp.Categories = append(resp.Categories, category.Name)
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// apiGetOutputFeed serves the user's merged posts as an Atom or RSS 2.0
+// document for feed readers, limited by the limit query parameter
+func apiGetOutputFeed(format, contentType string) func(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	return func(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+		limit, err := queryInt(r.URL.Query().Get("limit"), apiDefaultPostLimit)
+		if err != nil || limit < 1 || limit > apiMaxPostLimit {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(apiMaxPostLimit))
+			return
+		}
+
+		posts, err := s.db.GetPostsByUser(r.Context(), database.GetPostsByUserParams{
+			UserID: user.ID,
+			Limit:  int32(limit),
+		})
+		if err != nil {
+			respondWithServerError(w, err)
+			return
+		}
+
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		selfURL := scheme + "://" + r.Host + r.URL.Path
+
+		data, err := renderOutputFeed(format, user, posts, selfURL)
+		if err != nil {
+			respondWithServerError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
+
+func apiMarkPostRead(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := strconv.ParseInt(r.PathValue("postID"), 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid post id")
+		return
+	}
+
+	_, err = s.db.GetPostByID(r.Context(), int32(postID))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "no post with this id exists")
+		return
+	}
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	err = s.db.MarkPostRead(r.Context(), database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: int32(postID),
+		ReadAt: time.Now(),
+	})
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiMarkFeedRead marks every post from a feed read for the user
+func apiMarkFeedRead(s *state, w http.ResponseWriter, r *http.Request, user database.User) {
+	var params struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil || params.URL == "" {
+		respondWithError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+
+	marked, err := s.db.MarkFeedRead(r.Context(), database.MarkFeedReadParams{
+		UserID: user.ID,
+		ReadAt: time.Now(),
+		Url:    params.URL,
+	})
+	if err != nil {
+		respondWithServerError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]int64{"marked": marked})
+}
+
+func queryInt(value string, defaultValue int) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
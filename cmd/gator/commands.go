@@ -5,8 +5,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Breadumi/aggreGator/internal/database"
@@ -79,7 +82,8 @@ func handlerRegister(s *state, cmd command) error {
 
 	s.cfg.SetUser(user.Name)
 	fmt.Printf("User %s has been created:\n", user.Name)
-	userJSON, err := json.MarshalIndent(user, "", "  ")
+	// leave the API key out of the output; gator apikey shows it on request
+	userJSON, err := json.MarshalIndent(toAPIUser(user), "", "  ")
 	if err != nil {
 		return err
 	}
@@ -126,7 +130,14 @@ func handlerUsers(s *state, cmd command) error {
 
 func handlerFeeds(s *state, cmd command) error {
 
-	if cmd.args != nil || len(cmd.args) > 0 {
+	fs := flag.NewFlagSet("feeds", flag.ContinueOnError)
+	health := fs.Bool("health", false, "show fetch health for each feed")
+
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
 		return errors.New("too many arguments: expected none")
 	}
 
@@ -143,77 +154,129 @@ func handlerFeeds(s *state, cmd command) error {
 		fmt.Printf("Name: %s\n", feed.Name)
 		fmt.Printf("URL: %s\n", feed.Url)
 		fmt.Printf("User: %s\n", userID.Name)
+		if *health {
+			printFeedHealth(feed)
+		}
 	}
 
 	return nil
 }
 
-func handlerAgg(s *state, cmd command) error {
+func printFeedHealth(feed database.Feed) {
+	switch {
+	case feed.ConsecutiveFailures == 0 && feed.LastSucceededAt.Valid:
+		fmt.Println("Status: OK")
+	case feed.ConsecutiveFailures == 0:
+		fmt.Println("Status: not fetched yet")
+	case feed.BackoffUntil.Valid && feed.BackoffUntil.Time.After(time.Now()):
+		fmt.Printf("Status: failing (%d in a row), retrying after %s\n",
+			feed.ConsecutiveFailures, feed.BackoffUntil.Time.Format(time.DateTime))
+	default:
+		fmt.Printf("Status: failing (%d in a row)\n", feed.ConsecutiveFailures)
+	}
+
+	if feed.LastSucceededAt.Valid {
+		fmt.Printf("Last success: %s\n", feed.LastSucceededAt.Time.Format(time.DateTime))
+	} else {
+		fmt.Println("Last success: never")
+	}
+	if feed.LastError.Valid {
+		fmt.Printf("Last error: %s\n", feed.LastError.String)
+	}
+}
+
+func handlerPost(s *state, cmd command) error {
+
 	if len(cmd.args) != 1 {
-		return errors.New("expected 1 arguments <string:timeBetweenRequests>")
+		return errors.New("expected 1 argument <post id>")
 	}
-	timeBetweenRequests := cmd.args[0]
-	t, err := time.ParseDuration(timeBetweenRequests)
+
+	postIDs, err := parsePostIDs(cmd.args)
 	if err != nil {
 		return err
 	}
-	ticker := time.NewTicker(t)
-	fmt.Println("Press ctrl+C to cancel scraping")
-	fmt.Println("Scraping...")
-	for ; ; <-ticker.C {
-		scrapeFeeds(s)
-	}
-}
 
-func scrapeFeeds(s *state) error {
-	nextFeed, err := s.db.GetNextFeedToFetch(context.Background())
+	ctx := context.Background()
+
+	post, err := s.db.GetPostByID(ctx, postIDs[0])
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("post %d not found", postIDs[0])
+	}
 	if err != nil {
 		return err
 	}
 
-	fetchedParams := database.MarkFeedFetchedParams{
-		ID:        nextFeed.ID,
-		UpdatedAt: time.Now(),
+	feed, err := s.db.GetFeedByID(ctx, post.FeedID)
+	if err != nil {
+		return err
 	}
 
-	err = s.db.MarkFeedFetched(context.Background(), fetchedParams)
+	categories, err := s.db.GetCategoriesForPost(ctx, post.ID)
 	if err != nil {
 		return err
 	}
 
-	rss, err := fetchFeed(context.Background(), nextFeed.Url)
+	printPostDetail(post, feed, categories)
+	return nil
+}
+
+func handlerPrune(s *state, cmd command) error {
+
+	if len(cmd.args) != 1 {
+		return errors.New("expected 1 argument <age>")
+	}
+
+	age, err := parseAge(cmd.args[0])
 	if err != nil {
 		return err
 	}
 
-	for _, item := range rss.Channel.Item {
+	deleted, err := s.db.DeleteUnstarredPostsBefore(context.Background(), time.Now().Add(-age))
+	if err != nil {
+		return err
+	}
 
-		parsedTime, err := time.Parse(time.RFC1123, item.PubDate)
-		if err != nil {
-			parsedTime, err = time.Parse(time.RFC1123Z, item.PubDate)
-			if err != nil {
-				log.Println(err)
-				continue
-			}
-		}
+	fmt.Printf("Deleted %d posts published more than %s ago\n", deleted, cmd.args[0])
+	return nil
+}
 
-		postParams := database.CreatePostParams{
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			Title:       item.Title,
-			Url:         item.Link,
-			Description: item.Description,
-			PublishedAt: parsedTime,
-			FeedID:      nextFeed.ID,
-		}
-		post, err := s.db.CreatePost(context.Background(), postParams)
-		if err != nil && err != sql.ErrNoRows {
-			return err
+// parseAge parses a duration such as 12h, additionally accepting a number of days such as 30d
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q: expected <number><unit>, e.g. 30d", s)
 		}
-		fmt.Printf("Saved post %s from %s for user %s\n", post.Title, nextFeed.Name, s.cfg.CurrentUserName)
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
 
+	age, err := time.ParseDuration(s)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q: expected <number><unit>, e.g. 30d", s)
 	}
+	return age, nil
+}
 
-	return nil
+// parseFlags parses the flags defined on fs, which may appear before, after or
+// between positional arguments, and returns the positional arguments in order.
+// Everything after a "--" argument is positional.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
 
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		consumed := len(args) - fs.NArg()
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, fs.Args()...), nil
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPrunedPostsAreNotSavedAgain(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	feed := newFeedServer(t, rssItem("old", "Old"))
+
+	run(t, s, handlerRegister, "register", "alice")
+	run(t, s, middlewareLoggedIn(handlerAddFeed), "addfeed", "Example", feed.URL)
+	dbFeed, err := s.db.GetFeedByURL(ctx, feed.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := scrapeFeed(ctx, s, dbFeed); err != nil {
+		t.Fatal(err)
+	}
+
+	run(t, s, handlerPrune, "prune", "30d")
+	if posts := postsForUser(t, s, "alice"); len(posts) != 0 {
+		t.Fatalf("got %d posts after pruning, want 0", len(posts))
+	}
+
+	// the feed still lists the pruned item alongside a new one
+	recent := time.Now().UTC().Format(time.RFC1123Z)
+	feed.setItems(rssItem("old", "Old"),
+		`<item><guid>new</guid><title>New</title><pubDate>`+recent+`</pubDate></item>`)
+	saved, _, err := scrapeFeed(ctx, s, dbFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved != 1 {
+		t.Errorf("saved %d posts, want only the new one", saved)
+	}
+	posts := postsForUser(t, s, "alice")
+	if len(posts) != 1 || posts[0].Guid != "new" {
+		t.Errorf("got posts %+v, want only the new one", posts)
+	}
+}
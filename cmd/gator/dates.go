@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var errMissingPubDate = errors.New("item has no publication date")
+
+// pubDateLayouts covers the variants seen in real feeds: RFC 822 dates from
+// RSS 2.0 with and without weekdays, seconds or zero-padded days, RFC 3339
+// from Atom and JSON Feed, and the W3C date formats used by Dublin Core
+var pubDateLayouts = []string{
+	time.RFC1123,
+	time.RFC1123Z,
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 -0700 (MST)",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 January 2006 15:04:05 MST",
+	"Mon, 2 January 2006 15:04:05 -0700",
+	"Monday, 2 January 2006 15:04:05 MST",
+	"Monday, 2 January 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 06 15:04:05 MST",
+	"Mon, 2 Jan 06 15:04:05 -0700",
+	time.RFC850,
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.ANSIC,
+	time.UnixDate,
+}
+
+// zoneOffsets maps the zone abbreviations feeds commonly use to their UTC
+// offsets. time.Parse only knows the abbreviations of the local time zone and
+// otherwise silently treats a named zone as UTC. Ambiguous abbreviations such
+// as IST (India, Ireland or Israel) are deliberately missing, so dates using
+// them are treated as unreadable rather than guessed at, unless IST is the
+// local zone.
+var zoneOffsets = map[string]int{
+	"UT":   0,
+	"UTC":  0,
+	"GMT":  0,
+	"Z":    0,
+	"EST":  -5 * 60 * 60,
+	"EDT":  -4 * 60 * 60,
+	"CST":  -6 * 60 * 60,
+	"CDT":  -5 * 60 * 60,
+	"MST":  -7 * 60 * 60,
+	"MDT":  -6 * 60 * 60,
+	"PST":  -8 * 60 * 60,
+	"PDT":  -7 * 60 * 60,
+	"AKST": -9 * 60 * 60,
+	"AKDT": -8 * 60 * 60,
+	"HST":  -10 * 60 * 60,
+	"BST":  1 * 60 * 60,
+	"WET":  0,
+	"WEST": 1 * 60 * 60,
+	"CET":  1 * 60 * 60,
+	"CEST": 2 * 60 * 60,
+	"EET":  2 * 60 * 60,
+	"EEST": 3 * 60 * 60,
+	"MSK":  3 * 60 * 60,
+	"JST":  9 * 60 * 60,
+	"KST":  9 * 60 * 60,
+	"AEST": 10 * 60 * 60,
+	"AEDT": 11 * 60 * 60,
+	"NZST": 12 * 60 * 60,
+	"NZDT": 13 * 60 * 60,
+}
+
+// parsePubDate normalizes a feed item's publication date to UTC, trying each
+// known layout in turn. Posts are stored in TIMESTAMP columns, which drop the
+// offset, so only UTC times compare correctly across feeds.
+func parsePubDate(pubDate string) (time.Time, error) {
+	// collapse stray whitespace and line breaks left in by some generators
+	pubDate = strings.Join(strings.Fields(pubDate), " ")
+	if pubDate == "" {
+		return time.Time{}, errMissingPubDate
+	}
+
+	for _, layout := range pubDateLayouts {
+		parsedTime, err := time.Parse(layout, pubDate)
+		if err != nil {
+			continue
+		}
+		// a numeric offset wins over any abbreviation that comes with it
+		if strings.Contains(layout, "MST") && !strings.Contains(layout, "-0700") {
+			parsedTime, err = applyZoneAbbreviation(parsedTime)
+			if err != nil {
+				continue
+			}
+		}
+		return parsedTime.UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized publication date %q", pubDate)
+}
+
+// applyZoneAbbreviation corrects times parsed with an abbreviation time.Parse
+// did not know, which it gives a zero offset, and rejects abbreviations
+// missing from zoneOffsets. time.Parse also accepts a numeric offset in place
+// of an abbreviation; rejecting it leaves it to the layouts with -0700.
+func applyZoneAbbreviation(t time.Time) (time.Time, error) {
+	name, offset := t.Zone()
+	if offset != 0 {
+		// an abbreviation of the local time zone
+		return t, nil
+	}
+	knownOffset, ok := zoneOffsets[strings.ToUpper(name)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("unknown time zone %q", name)
+	}
+	if knownOffset == 0 {
+		return t, nil
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(),
+		time.FixedZone(name, knownOffset)), nil
+}
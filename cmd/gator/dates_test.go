@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParsePubDate(t *testing.T) {
+	tests := []struct {
+		name    string
+		pubDate string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:    "RFC 822 with seconds",
+			pubDate: "Mon, 02 Jan 2006 15:04:05 +0000",
+			want:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    "RFC 822 without seconds",
+			pubDate: "Mon, 02 Jan 2006 15:04 +0000",
+			want:    time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC),
+		},
+		{
+			name:    "single-digit day",
+			pubDate: "Mon, 2 Jan 2006 15:04:05 GMT",
+			want:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    "numeric offset",
+			pubDate: "Mon, 02 Jan 2006 15:04:05 -0500",
+			want:    time.Date(2006, 1, 2, 20, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    "EDT",
+			pubDate: "Thu, 01 Jun 2006 10:00:00 EDT",
+			want:    time.Date(2006, 6, 1, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "PST",
+			pubDate: "Mon, 02 Jan 2006 15:04:05 PST",
+			want:    time.Date(2006, 1, 2, 23, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    "GMT",
+			pubDate: "Mon, 02 Jan 2006 15:04:05 GMT",
+			want:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    "stray whitespace",
+			pubDate: "  Mon, 02 Jan 2006\n  15:04:05 GMT ",
+			want:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    "RFC 3339",
+			pubDate: "2006-01-02T15:04:05+02:00",
+			want:    time.Date(2006, 1, 2, 13, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    "RFC 3339 UTC with fractional seconds",
+			pubDate: "2006-01-02T15:04:05.5Z",
+			want:    time.Date(2006, 1, 2, 15, 4, 5, 500000000, time.UTC),
+		},
+		{
+			name:    "date only",
+			pubDate: "2006-01-02",
+			want:    time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "empty",
+			pubDate: "",
+			wantErr: true,
+		},
+		{
+			name:    "ambiguous zone",
+			pubDate: "Mon, 02 Jan 2006 15:04:05 IST",
+			wantErr: true,
+		},
+		{
+			name:    "garbage",
+			pubDate: "yesterday",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if localZoneIs("IST") && tt.name == "ambiguous zone" {
+				t.Skip("IST is the local time zone, which time.Parse knows")
+			}
+			got, err := parsePubDate(tt.pubDate)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsePubDate(%q) = %v, want an error", tt.pubDate, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePubDate(%q): %v", tt.pubDate, err)
+			}
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("parsePubDate(%q) = %v, want %v", tt.pubDate, got, tt.want)
+			}
+		})
+	}
+}
+
+// localZoneIs reports whether name is an abbreviation of the local time zone
+func localZoneIs(name string) bool {
+	parsed, err := time.Parse(time.RFC1123, "Mon, 02 Jan 2006 15:04:05 "+name)
+	_, offset := parsed.Zone()
+	return err == nil && offset != 0
+}
+
+func TestScrapeFeedStoresPublicationDatesInUTC(t *testing.T) {
+	if localZoneIs("IST") {
+		t.Skip("IST is the local time zone, which time.Parse knows")
+	}
+
+	ctx := context.Background()
+	s := newTestState(t)
+	feed := newFeedServer(t,
+		`<item><guid>gmt</guid><title>Noon GMT</title><pubDate>Thu, 01 Jun 2006 12:00:00 GMT</pubDate></item>`,
+		`<item><guid>edt</guid><title>Ten EDT</title><pubDate>Thu, 01 Jun 2006 10:00:00 EDT</pubDate></item>`,
+		`<item><guid>undated</guid><title>Undated</title></item>`,
+		`<item><guid>ist</guid><title>IST</title><pubDate>Thu, 01 Jun 2006 10:00:00 IST</pubDate></item>`,
+	)
+
+	run(t, s, handlerRegister, "register", "alice")
+	run(t, s, middlewareLoggedIn(handlerAddFeed), "addfeed", "Example", feed.URL)
+	dbFeed, err := s.db.GetFeedByURL(ctx, feed.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now()
+	if _, _, err := scrapeFeed(ctx, s, dbFeed); err != nil {
+		t.Fatal(err)
+	}
+
+	posts := postsForUser(t, s, "alice")
+	if len(posts) != 4 {
+		t.Fatalf("got %d posts, want 4", len(posts))
+	}
+
+	// items without a usable date are stamped with the fetch time, so they sort first
+	for _, post := range posts[:2] {
+		if !post.PublishedAtInferred || post.PublishedAt.Before(before.UTC().Add(-time.Second)) {
+			t.Errorf("post %s: published %v inferred %v, want the fetch time", post.Guid, post.PublishedAt, post.PublishedAtInferred)
+		}
+	}
+
+	// 10:00 EDT is 14:00 UTC, two hours after noon GMT
+	if posts[2].Guid != "edt" || posts[3].Guid != "gmt" {
+		t.Fatalf("dated posts sorted %s, %s; want edt, gmt", posts[2].Guid, posts[3].Guid)
+	}
+	if want := time.Date(2006, 6, 1, 14, 0, 0, 0, time.UTC); !posts[2].PublishedAt.Equal(want) {
+		t.Errorf("EDT post published at %v, want %v", posts[2].PublishedAt, want)
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+)
+
+// unsafeFileNamePattern matches the characters replaced when an enclosure's
+// url is turned into a file name
+var unsafeFileNamePattern = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func handlerEnclosures(s *state, cmd command, user database.User) error {
+
+	fs := flag.NewFlagSet("enclosures", flag.ContinueOnError)
+	feedURL := fs.String("feed", "", "only list enclosures from the feed at this url")
+	pending := fs.Bool("pending", false, "only list enclosures the current user has not downloaded")
+
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 1 {
+		return errors.New("expected no more than 1 argument <limit> (default limit = 10)")
+	}
+
+	limit := 10
+	if len(args) == 1 {
+		limit, err = strconv.Atoi(args[0])
+		if err != nil || limit < 1 {
+			return errors.New("invalid limit argument: expected type <int>")
+		}
+	}
+
+	enclosures, err := s.db.GetEnclosuresByUser(context.Background(), database.GetEnclosuresByUserParams{
+		UserID:      user.ID,
+		FeedUrl:     sql.NullString{String: *feedURL, Valid: *feedURL != ""},
+		PendingOnly: *pending,
+		Limit:       int32(limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(enclosures) == 0 {
+		fmt.Println("No enclosures found")
+		return nil
+	}
+
+	for _, enclosure := range enclosures {
+		fmt.Printf("[%d] %s\n", enclosure.ID, enclosure.PostTitle)
+		fmt.Printf("  Feed: %s\n", enclosure.FeedName)
+		fmt.Printf("  Published: %s\n", enclosure.PublishedAt.Format(time.DateTime))
+		fmt.Printf("  URL: %s\n", enclosure.Url)
+		if details := enclosureDetails(enclosure.MimeType, enclosure.SizeBytes, enclosure.DurationSeconds); details != "" {
+			fmt.Printf("  %s\n", details)
+		}
+		if enclosure.DownloadPath.Valid {
+			fmt.Printf("  Downloaded: %s (%s)\n", enclosure.DownloadPath.String, enclosure.DownloadedAt.Time.Local().Format(time.DateTime))
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+func handlerDownload(s *state, cmd command, user database.User) error {
+
+	fs := flag.NewFlagSet("download", flag.ContinueOnError)
+	dir := fs.String("dir", "", "directory to download into, overriding download_dir in the config file")
+	pending := fs.Bool("pending", false, "download every enclosure the current user has not downloaded")
+	feedURL := fs.String("feed", "", "with --pending, only download enclosures from the feed at this url")
+	limit := fs.Int("limit", 10, "with --pending, maximum number of enclosures to download")
+	maxSize := fs.Int64("max-size", 0, "skip enclosures larger than this many megabytes")
+
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	if *pending == (len(args) > 0) {
+		return errors.New("expected either <enclosure id> arguments or --pending")
+	}
+	if *limit < 1 {
+		return errors.New("invalid limit argument: expected a positive <int>")
+	}
+	if *maxSize < 0 {
+		return errors.New("invalid max-size argument: expected a positive <int>")
+	}
+
+	downloadDir := *dir
+	if downloadDir == "" {
+		downloadDir, err = s.cfg.GetDownloadDir()
+		if err != nil {
+			return err
+		}
+	}
+
+	// an interrupted download keeps its partial file, and is resumed by the next run
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var enclosures []database.Enclosure
+	if *pending {
+		rows, err := s.db.GetEnclosuresByUser(ctx, database.GetEnclosuresByUserParams{
+			UserID:      user.ID,
+			FeedUrl:     sql.NullString{String: *feedURL, Valid: *feedURL != ""},
+			PendingOnly: true,
+			Limit:       int32(*limit),
+		})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			enclosures = append(enclosures, database.Enclosure{
+				ID:              row.ID,
+				PostID:          row.PostID,
+				Url:             row.Url,
+				MimeType:        row.MimeType,
+				SizeBytes:       row.SizeBytes,
+				DurationSeconds: row.DurationSeconds,
+			})
+		}
+	} else {
+		for _, arg := range args {
+			enclosureID, err := strconv.ParseInt(arg, 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid enclosure id %q: expected type <int>", arg)
+			}
+			enclosure, err := s.db.GetEnclosureByID(ctx, int32(enclosureID))
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("enclosure %d not found", enclosureID)
+			}
+			if err != nil {
+				return err
+			}
+			enclosures = append(enclosures, enclosure)
+		}
+	}
+
+	if len(enclosures) == 0 {
+		fmt.Println("No enclosures to download")
+		return nil
+	}
+
+	failed := 0
+	for _, enclosure := range enclosures {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		download, err := s.db.GetEnclosureDownload(ctx, database.GetEnclosureDownloadParams{
+			UserID:      user.ID,
+			EnclosureID: enclosure.ID,
+		})
+		if err == nil && fileHasSize(download.Path, download.SizeBytes) {
+			fmt.Printf("Enclosure %d is already downloaded to %s\n", enclosure.ID, download.Path)
+			continue
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		filePath, size, err := downloadEnclosure(ctx, enclosure, downloadDir, *maxSize*1024*1024)
+		if err != nil {
+			fmt.Printf("Failed to download enclosure %d: %v\n", enclosure.ID, err)
+			failed++
+			continue
+		}
+
+		err = s.db.MarkEnclosureDownloaded(ctx, database.MarkEnclosureDownloadedParams{
+			UserID:       user.ID,
+			EnclosureID:  enclosure.ID,
+			Path:         filePath,
+			SizeBytes:    size,
+			DownloadedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Downloaded enclosure %d to %s (%s)\n", enclosure.ID, filePath, formatSize(size))
+		if enclosure.SizeBytes.Valid && enclosure.SizeBytes.Int64 != size {
+			fmt.Printf("  Note: the feed lists this enclosure as %s\n", formatSize(enclosure.SizeBytes.Int64))
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d downloads failed", failed, len(enclosures))
+	}
+	return nil
+}
+
+// downloadEnclosure saves an enclosure into dir and returns the file's path
+// and size. Data is written to a .part file that is renamed once the server's
+// reported size has been received in full, and an existing .part file is
+// resumed with a Range request. maxSize, if positive, is the largest file
+// accepted in bytes.
+func downloadEnclosure(ctx context.Context, enclosure database.Enclosure, dir string, maxSize int64) (string, int64, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", 0, err
+	}
+	filePath := filepath.Join(dir, enclosureFileName(enclosure))
+	partPath := filePath + ".part"
+
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", enclosure.Url, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("User-Agent", "gator")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	// total is the size of the complete file, or -1 if the server didn't say
+	var total int64
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return "", 0, fmt.Errorf("unexpected Content-Range %q resuming at byte %d", resp.Header.Get("Content-Range"), offset)
+		}
+		total = size
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// the partial file may already hold every byte
+		_, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || size != offset {
+			os.Remove(partPath)
+			return "", 0, errors.New("server refused to resume the download, so the partial file was discarded: run download again to start over")
+		}
+		return filePath, offset, os.Rename(partPath, filePath)
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		// the server ignored the Range header and is sending the whole file
+		offset = 0
+		total = resp.ContentLength
+	default:
+		return "", 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if maxSize > 0 && total > maxSize {
+		return "", 0, fmt.Errorf("file is %s, larger than the %s limit", formatSize(total), formatSize(maxSize))
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return "", 0, err
+	}
+
+	body := io.Reader(resp.Body)
+	if maxSize > 0 {
+		// read one byte past the limit so oversized files without a reported size are caught
+		body = io.LimitReader(resp.Body, maxSize-offset+1)
+	}
+	written, err := io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	size := offset + written
+	if err != nil {
+		return "", 0, fmt.Errorf("%w (%s saved, run download again to resume)", err, formatSize(size))
+	}
+
+	if maxSize > 0 && size > maxSize {
+		os.Remove(partPath)
+		return "", 0, fmt.Errorf("file is larger than the %s limit", formatSize(maxSize))
+	}
+	if total >= 0 && size != total {
+		return "", 0, fmt.Errorf("incomplete download: received %d of %d bytes, run download again to resume", size, total)
+	}
+
+	if err := os.Rename(partPath, filePath); err != nil {
+		return "", 0, err
+	}
+	return filePath, size, nil
+}
+
+// enclosureFileName names a download after the last element of the
+// enclosure's url path, prefixed with the enclosure id so episodes that share
+// a file name don't overwrite each other
+func enclosureFileName(enclosure database.Enclosure) string {
+	name := ""
+	if u, err := url.Parse(enclosure.Url); err == nil {
+		name = path.Base(u.Path)
+	}
+	name = strings.Trim(unsafeFileNamePattern.ReplaceAllString(name, "_"), "._")
+	if name == "" {
+		name = "enclosure"
+	}
+	return fmt.Sprintf("%d-%s", enclosure.ID, name)
+}
+
+// parseContentRange parses a Content-Range header such as "bytes 100-199/1000"
+// or "bytes */1000", returning -1 for a missing start or unknown total
+func parseContentRange(header string) (start, total int64, ok bool) {
+	rangeSpec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	byteRange, size, ok := strings.Cut(rangeSpec, "/")
+	if !ok {
+		return 0, 0, false
+	}
+
+	start, total = -1, -1
+	if byteRange != "*" {
+		first, _, ok := strings.Cut(byteRange, "-")
+		if !ok {
+			return 0, 0, false
+		}
+		n, err := strconv.ParseInt(first, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		start = n
+	}
+	if size != "*" {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		total = n
+	}
+	return start, total, true
+}
+
+// fileHasSize reports whether a file exists at filePath with the given size
+func fileHasSize(filePath string, size int64) bool {
+	info, err := os.Stat(filePath)
+	return err == nil && info.Size() == size
+}
+
+// enclosureDetails describes an enclosure's type, size and duration, leaving
+// out whichever the feed didn't give
+func enclosureDetails(mimeType string, size sql.NullInt64, duration sql.NullInt32) string {
+	var details []string
+	if mimeType != "" {
+		details = append(details, mimeType)
+	}
+	if size.Valid {
+		details = append(details, formatSize(size.Int64))
+	}
+	if duration.Valid {
+		details = append(details, formatDuration(duration.Int32))
+	}
+	return strings.Join(details, ", ")
+}
+
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, exp := float64(bytes)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "KMGT"[exp])
+}
+
+// formatDuration formats seconds as h:mm:ss, or m:ss under an hour
+func formatDuration(seconds int32) string {
+	h, m, sec := seconds/3600, seconds/60%60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, sec)
+	}
+	return fmt.Sprintf("%d:%02d", m, sec)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+)
+
+// episode is the file the test servers serve
+var episode = []byte(strings.Repeat("0123456789", 100))
+
+// serveEpisode honours Range requests, answering 206 or 416 as appropriate
+func serveEpisode(w http.ResponseWriter, r *http.Request) {
+	http.ServeContent(w, r, "episode.mp3", time.Time{}, bytes.NewReader(episode))
+}
+
+func TestDownloadEnclosure(t *testing.T) {
+	tests := []struct {
+		name      string
+		part      []byte // contents of an earlier partial download
+		handler   http.HandlerFunc
+		maxSize   int64
+		wantRange string
+		wantErr   string
+	}{
+		{
+			name:    "fresh",
+			handler: serveEpisode,
+		},
+		{
+			name:      "resumed",
+			part:      episode[:400],
+			handler:   serveEpisode,
+			wantRange: "bytes=400-",
+		},
+		{
+			name: "server ignores Range",
+			part: []byte("stale bytes from another file"),
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write(episode)
+			},
+			wantRange: "bytes=29-",
+		},
+		{
+			name:      "already complete",
+			part:      episode,
+			handler:   serveEpisode,
+			wantRange: "bytes=1000-",
+		},
+		{
+			name:    "larger than the reported size limit",
+			handler: serveEpisode,
+			maxSize: 999,
+			wantErr: "larger than the 999 B limit",
+		},
+		{
+			name: "larger than the limit without a reported size",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				// flushing before writing the body leaves Content-Length unset
+				w.(http.Flusher).Flush()
+				w.Write(episode)
+			},
+			maxSize: 999,
+			wantErr: "larger than the 999 B limit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRange string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRange = r.Header.Get("Range")
+				tt.handler(w, r)
+			}))
+			t.Cleanup(server.Close)
+
+			dir := t.TempDir()
+			enclosure := database.Enclosure{ID: 7, Url: server.URL + "/episodes/episode.mp3"}
+			partPath := filepath.Join(dir, "7-episode.mp3.part")
+			if tt.part != nil {
+				if err := os.WriteFile(partPath, tt.part, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			filePath, size, err := downloadEnclosure(context.Background(), enclosure, dir, tt.maxSize)
+			if gotRange != tt.wantRange {
+				t.Errorf("Range = %q, want %q", gotRange, tt.wantRange)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := os.Stat(filepath.Join(dir, "7-episode.mp3")); !os.IsNotExist(err) {
+					t.Errorf("oversized download was saved: stat err = %v", err)
+				}
+				if _, err := os.Stat(partPath); !os.IsNotExist(err) {
+					t.Errorf("oversized download left a partial file: stat err = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := filepath.Join(dir, "7-episode.mp3"); filePath != want {
+				t.Errorf("path = %q, want %q", filePath, want)
+			}
+			if size != int64(len(episode)) {
+				t.Errorf("size = %d, want %d", size, len(episode))
+			}
+			data, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, episode) {
+				t.Errorf("downloaded %d bytes that don't match the episode", len(data))
+			}
+			if _, err := os.Stat(partPath); !os.IsNotExist(err) {
+				t.Errorf("partial file left behind: stat err = %v", err)
+			}
+		})
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header       string
+		start, total int64
+		ok           bool
+	}{
+		{"bytes 100-199/1000", 100, 1000, true},
+		{"bytes 0-0/1", 0, 1, true},
+		{"bytes 100-199/*", 100, -1, true},
+		{"bytes */1000", -1, 1000, true},
+		{"", 0, 0, false},
+		{"items 0-9/10", 0, 0, false},
+		{"bytes 100-199", 0, 0, false},
+		{"bytes 100/1000", 0, 0, false},
+		{"bytes x-199/1000", 0, 0, false},
+		{"bytes 100-199/many", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		start, total, ok := parseContentRange(tt.header)
+		if start != tt.start || total != tt.total || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v; want %d, %d, %v",
+				tt.header, start, total, ok, tt.start, tt.total, tt.ok)
+		}
+	}
+}
+
+func TestEnclosureFileName(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/episodes/42.mp3", "3-42.mp3"},
+		{"https://example.com/episodes/42.mp3?token=abc#t=10", "3-42.mp3"},
+		{"https://example.com/episodes/My%20Show%20(Ep%201).m4a", "3-My_Show_Ep_1_.m4a"},
+		{"https://example.com/.hidden", "3-hidden"},
+		{"https://example.com/", "3-enclosure"},
+		{"https://example.com", "3-enclosure"},
+		{"://not a url", "3-enclosure"},
+	}
+
+	for _, tt := range tests {
+		if got := enclosureFileName(database.Enclosure{ID: 3, Url: tt.url}); got != tt.want {
+			t.Errorf("enclosureFileName(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
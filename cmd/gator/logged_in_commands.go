@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Breadumi/aggreGator/internal/database"
@@ -97,7 +100,7 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 	}
 
 	for _, row := range userFeedFollows {
-		fmt.Printf("%s\n", row.FeedName)
+		fmt.Printf("%s (%d unread)\n", row.FeedName, row.UnreadCount)
 	}
 
 	return nil
@@ -125,29 +128,338 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 
 func handlerBrowse(s *state, cmd command, user database.User) error {
 
-	if len(cmd.args) > 1 {
+	fs := flag.NewFlagSet("browse", flag.ContinueOnError)
+	offset := fs.Int("offset", 0, "number of posts to skip")
+	since := fs.String("since", "", "only show posts published at or after this date")
+	until := fs.String("until", "", "only show posts published before this date")
+	feedURL := fs.String("feed", "", "only show posts from the feed at this url")
+	category := fs.String("category", "", "only show posts in this category")
+	author := fs.String("author", "", "only show posts by this author")
+	unread := fs.Bool("unread", false, "only show posts the current user has not read")
+
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 1 {
 		return errors.New("expected no more than 1 argument <post limit> (default limit = 2)")
 	}
 
 	var limit int
-	var err error
 
-	if len(cmd.args) == 1 {
-		limit, err = strconv.Atoi(cmd.args[0])
-		if err != nil {
+	if len(args) == 1 {
+		limit, err = strconv.Atoi(args[0])
+		if err != nil || limit < 1 {
 			return errors.New("invalid limit argument: expected type <int>")
 		}
 	} else {
 		limit = 2
 	}
 
+	if *offset < 0 {
+		return errors.New("invalid offset argument: expected a positive <int>")
+	}
+
 	paramsPostsByUser := database.GetPostsByUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
+		UserID:     user.ID,
+		FeedUrl:    sql.NullString{String: *feedURL, Valid: *feedURL != ""},
+		Category:   sql.NullString{String: *category, Valid: *category != ""},
+		Author:     sql.NullString{String: *author, Valid: *author != ""},
+		UnreadOnly: *unread,
+		Limit:      int32(limit),
+		Offset:     int32(*offset),
+	}
+
+	if paramsPostsByUser.Since, err = parseDateFlag("since", *since); err != nil {
+		return err
+	}
+	if paramsPostsByUser.Until, err = parseDateFlag("until", *until); err != nil {
+		return err
+	}
+
+	posts, err := s.db.GetPostsByUser(context.Background(), paramsPostsByUser)
+	if err != nil {
+		return err
+	}
+
+	if len(posts) == 0 {
+		fmt.Println("No posts found")
+		return nil
 	}
 
-	s.db.GetPostsByUser(context.Background(), paramsPostsByUser)
+	for _, post := range posts {
+		printPost(postSummary{
+			ID:                  post.ID,
+			Title:               post.Title,
+			FeedName:            post.FeedName,
+			Author:              post.Author,
+			Url:                 post.Url,
+			Description:         post.Description,
+			PublishedAt:         post.PublishedAt,
+			PublishedAtInferred: post.PublishedAtInferred,
+		})
+	}
+
+	if len(posts) == limit {
+		fmt.Printf("More posts may be available: use --offset %d\n", *offset+limit)
+	}
 
 	return nil
 
 }
+
+func handlerRead(s *state, cmd command, user database.User) error {
+
+	fs := flag.NewFlagSet("read", flag.ContinueOnError)
+	feedURL := fs.String("feed", "", "mark every post from the feed at this url read")
+
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	if *feedURL != "" {
+		if len(args) != 0 {
+			return errors.New("expected no <post id> arguments with --feed")
+		}
+
+		params := database.MarkFeedReadParams{
+			UserID: user.ID,
+			ReadAt: time.Now(),
+			Url:    *feedURL,
+		}
+
+		marked, err := s.db.MarkFeedRead(context.Background(), params)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Marked %d posts from %s read\n", marked, *feedURL)
+		return nil
+	}
+
+	if len(args) == 0 {
+		return errors.New("expected at least 1 argument <post id> or --feed <url>")
+	}
+
+	postIDs, err := parsePostIDs(args)
+	if err != nil {
+		return err
+	}
+
+	for _, postID := range postIDs {
+		params := database.MarkPostReadParams{
+			UserID: user.ID,
+			PostID: postID,
+			ReadAt: time.Now(),
+		}
+
+		if err := s.db.MarkPostRead(context.Background(), params); err != nil {
+			return fmt.Errorf("marking post %d read: %w", postID, err)
+		}
+
+		fmt.Printf("Marked post %d read\n", postID)
+	}
+
+	return nil
+}
+
+func handlerStar(s *state, cmd command, user database.User) error {
+
+	if len(cmd.args) == 0 {
+		return errors.New("expected at least 1 argument <post id>")
+	}
+
+	postIDs, err := parsePostIDs(cmd.args)
+	if err != nil {
+		return err
+	}
+
+	for _, postID := range postIDs {
+		params := database.StarPostParams{
+			UserID:    user.ID,
+			PostID:    postID,
+			CreatedAt: time.Now(),
+		}
+
+		if err := s.db.StarPost(context.Background(), params); err != nil {
+			return fmt.Errorf("starring post %d: %w", postID, err)
+		}
+
+		fmt.Printf("Starred post %d\n", postID)
+	}
+
+	return nil
+}
+
+func handlerUnstar(s *state, cmd command, user database.User) error {
+
+	if len(cmd.args) == 0 {
+		return errors.New("expected at least 1 argument <post id>")
+	}
+
+	postIDs, err := parsePostIDs(cmd.args)
+	if err != nil {
+		return err
+	}
+
+	for _, postID := range postIDs {
+		params := database.UnstarPostParams{
+			UserID: user.ID,
+			PostID: postID,
+		}
+
+		removed, err := s.db.UnstarPost(context.Background(), params)
+		if err != nil {
+			return fmt.Errorf("unstarring post %d: %w", postID, err)
+		}
+
+		if removed == 0 {
+			fmt.Printf("Post %d was not starred\n", postID)
+		} else {
+			fmt.Printf("Unstarred post %d\n", postID)
+		}
+	}
+
+	return nil
+}
+
+func handlerStarred(s *state, cmd command, user database.User) error {
+
+	if len(cmd.args) != 0 {
+		return errors.New("expected no arguments")
+	}
+
+	posts, err := s.db.GetStarredPostsForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(posts) == 0 {
+		fmt.Printf("%s has no starred posts\n", user.Name)
+		return nil
+	}
+
+	for _, post := range posts {
+		printPost(postSummary{
+			ID:                  post.ID,
+			Title:               post.Title,
+			FeedName:            post.FeedName,
+			Author:              post.Author,
+			Url:                 post.Url,
+			Description:         post.Description,
+			PublishedAt:         post.PublishedAt,
+			PublishedAtInferred: post.PublishedAtInferred,
+		})
+	}
+
+	return nil
+}
+
+func handlerSearch(s *state, cmd command, user database.User) error {
+
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	all := fs.Bool("all", false, "search posts from every feed, not just followed ones")
+	limit := fs.Int("limit", 10, "maximum number of results")
+
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return errors.New("expected at least 1 argument <query>")
+	}
+	if *limit < 1 {
+		return errors.New("invalid limit argument: expected a positive <int>")
+	}
+
+	params := database.SearchPostsParams{
+		Query:    strings.Join(args, " "),
+		AllFeeds: *all,
+		UserID:   user.ID,
+		Limit:    int32(*limit),
+	}
+
+	posts, err := s.db.SearchPosts(context.Background(), params)
+	if err != nil {
+		return err
+	}
+
+	if len(posts) == 0 {
+		fmt.Println("No posts found")
+		return nil
+	}
+
+	for _, post := range posts {
+		printPost(postSummary{
+			ID:                  post.ID,
+			Title:               post.Title,
+			FeedName:            post.FeedName,
+			Url:                 post.Url,
+			Description:         post.Description,
+			PublishedAt:         post.PublishedAt,
+			PublishedAtInferred: post.PublishedAtInferred,
+		})
+	}
+
+	return nil
+}
+
+// parsePostIDs parses the post ids shown in browse output
+func parsePostIDs(args []string) ([]int32, error) {
+	postIDs := make([]int32, 0, len(args))
+	for _, arg := range args {
+		postID, err := strconv.ParseInt(arg, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid post id %q: expected type <int>", arg)
+		}
+		postIDs = append(postIDs, int32(postID))
+	}
+	return postIDs, nil
+}
+
+// parseDateFlag parses an optional date given on the command line, accepting
+// the same formats as feed publication dates
+func parseDateFlag(name, value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := parsePubDate(value)
+	if err != nil {
+		return sql.NullTime{}, fmt.Errorf("invalid %s argument: expected a date such as 2006-01-02", name)
+	}
+	return sql.NullTime{Time: t, Valid: true}, nil
+}
+
+func handlerAPIKey(s *state, cmd command, user database.User) error {
+
+	fs := flag.NewFlagSet("apikey", flag.ContinueOnError)
+	rotate := fs.Bool("rotate", false, "replace the current API key with a new one")
+
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("expected no arguments")
+	}
+
+	if *rotate {
+		params := database.RotateUserAPIKeyParams{
+			ID:        user.ID,
+			UpdatedAt: time.Now(),
+		}
+
+		user, err = s.db.RotateUserAPIKey(context.Background(), params)
+		if err != nil {
+			return err
+		}
+		fmt.Println("API key rotated, the previous key no longer works")
+	}
+
+	fmt.Printf("API key for %s: %s\n", user.Name, user.ApiKey)
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestUnfollowHidesPostsAndResetCascades(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	feed := newFeedServer(t, rssItem("a", "First"), rssItem("b", "Second"))
+
+	run(t, s, handlerRegister, "register", "alice")
+	run(t, s, middlewareLoggedIn(handlerAddFeed), "addfeed", "Example", feed.URL)
+	run(t, s, handlerRegister, "register", "bob")
+	run(t, s, middlewareLoggedIn(handlerFollow), "follow", feed.URL)
+
+	if results := scrapeFeeds(ctx, nil, s, testAggOptions); len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("scrape: got %+v", results)
+	}
+	if posts := postsForUser(t, s, "bob"); len(posts) != 2 {
+		t.Fatalf("bob sees %d posts while following, want 2", len(posts))
+	}
+
+	run(t, s, middlewareLoggedIn(handlerUnfollow), "unfollow", feed.URL)
+
+	bob, err := s.db.GetUserByName(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	follows, err := s.db.GetFeedFollowsForUser(ctx, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 0 {
+		t.Errorf("bob still follows %d feeds after unfollowing", len(follows))
+	}
+	if posts := postsForUser(t, s, "bob"); len(posts) != 0 {
+		t.Errorf("bob sees %d posts after unfollowing, want 0", len(posts))
+	}
+	posts := postsForUser(t, s, "alice")
+	if len(posts) != 2 {
+		t.Fatalf("alice sees %d posts after bob unfollowed, want 2", len(posts))
+	}
+
+	// deleting the users removes the feeds they added along with their posts
+	run(t, s, handlerReset, "reset")
+
+	feeds, err := s.db.GetFeeds(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 0 {
+		t.Errorf("%d feeds left after reset", len(feeds))
+	}
+	if _, err := s.db.GetPostByID(ctx, posts[0].ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("post left after reset: err = %v", err)
+	}
+}
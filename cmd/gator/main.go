@@ -1,17 +1,12 @@
 package main
 
 import (
-	"bytes"
-	"database/sql"
+	"context"
 	"fmt"
-	"log"
 	"os"
 	"sort"
 
 	"github.com/Breadumi/aggreGator/internal/config"
-	"github.com/Breadumi/aggreGator/internal/database"
-	_ "github.com/lib/pq"
-	"github.com/pressly/goose/v3"
 )
 
 func main() {
@@ -22,36 +17,16 @@ func main() {
 		return
 	}
 
-	db, err := sql.Open("postgres", cfg.DbURL)
+	db, migrations, err := openStore(cfg.DbURL)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	// migrate databases, discard goose migration output but capture errors for later logging
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	var buf bytes.Buffer
-	log.SetOutput(&buf)
-
-	err = goose.Up(db, "sql/schema") // successful output is not printed to console
-
-	// close new output containing unwanted logs
-	w.Close()
-	r.Close()
-	os.Stdout = old
-
-	// log migration errors in console if necessary
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-
-	dbQueries := database.New(db)
-
 	s := state{
-		db:  dbQueries,
-		cfg: cfg,
+		db:         db,
+		cfg:        cfg,
+		migrations: migrations,
 	}
 
 	cmds := commands{
@@ -68,19 +43,49 @@ func main() {
 	cmds.register("users", handlerUsers,
 		"gator users\n\tList all registered users.")
 	cmds.register("agg", handlerAgg,
-		"gator agg [time]\n\tContinuously save new posts from the current user's followed feeds every unit of [time]. [time] is formatted <number><unit>, e.g. 5m is 5 minutes")
+		"gator agg [time] [--workers n] [--min-interval d] [--max-interval d] [--feed url] [--mine]\ngator agg --once [--workers n] [--feed url] [--mine]\n\tContinuously save new posts from the current user's followed feeds every unit of [time]. [time] is formatted <number><unit>, e.g. 5m is 5 minutes. Each interval, up to [n] due feeds are scraped concurrently (default 1). Each feed is polled according to how often it posts, between --min-interval (default 15m) and --max-interval (default 24h).\n\tWith --once, scrape every due feed a single time, print a table of results and exit with an error if any feed failed. --feed [url] only scrapes the feed at [url], even if it isn't due yet or is backing off after failures, and --mine only scrapes the current user's followed feeds.")
 	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed),
 		"gator addfeed [url]\n\tAdd a new feed at [url] and sets current user to follow [url].")
 	cmds.register("feeds", handlerFeeds,
-		"gator feeds\n\tList all tracked feeds.")
+		"gator feeds [--health]\n\tList all tracked feeds. --health also shows each feed's failure count, last success and last error.")
 	cmds.register("follow", middlewareLoggedIn(handlerFollow),
 		"gator follow [url]\n\tHave current user follow the feed at [url].")
 	cmds.register("following", middlewareLoggedIn(handlerFollowing),
-		"gator following\n\tList all feeds followed by current user.")
+		"gator following\n\tList all feeds followed by current user, with the number of unread posts in each.")
 	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow),
 		"gator unfollow [url]\n\tUnfollow feed at [url] for current user.")
 	cmds.register("browse", middlewareLoggedIn(handlerBrowse),
-		"gator browse [limit]\n\tBrowse posts from current user's followed feeds. Default [limit] is 2.")
+		"gator browse [limit] [--offset n] [--since date] [--until date] [--feed url] [--category name] [--author name] [--unread]\n\tBrowse posts from current user's followed feeds, newest first. Default [limit] is 2. --offset skips the first [n] posts, --since and --until limit posts to a publication date range and --feed only shows posts from the feed at [url]. --category and --author only show posts with that category or author, ignoring case. --unread hides posts the current user has read.")
+	cmds.register("post", handlerPost,
+		"gator post [post id]\n\tShow a post's author, categories and full content as plain text.")
+	cmds.register("read", middlewareLoggedIn(handlerRead),
+		"gator read [post id]...\ngator read --feed [url]\n\tMark posts read for current user, either by the ids shown in browse or every post from the feed at [url].")
+	cmds.register("star", middlewareLoggedIn(handlerStar),
+		"gator star [post id]...\n\tStar posts for current user. Starred posts are never pruned.")
+	cmds.register("unstar", middlewareLoggedIn(handlerUnstar),
+		"gator unstar [post id]...\n\tRemove the current user's star from posts.")
+	cmds.register("starred", middlewareLoggedIn(handlerStarred),
+		"gator starred\n\tList posts starred by current user.")
+	cmds.register("search", middlewareLoggedIn(handlerSearch),
+		"gator search [--all] [--limit n] [query]\n\tSearch post titles and descriptions in current user's followed feeds, best matches first. Use \"quotes\" for phrases, - to exclude a word and or between alternatives; put -- before a query starting with -. --all searches every feed. Default [n] is 10.")
+	cmds.register("enclosures", middlewareLoggedIn(handlerEnclosures),
+		"gator enclosures [limit] [--feed url] [--pending]\n\tList media files such as podcast episodes attached to posts in current user's followed feeds, newest first, with their type, size, duration and where current user downloaded them. Default [limit] is 10. --feed only lists enclosures from the feed at [url] and --pending hides enclosures current user has downloaded.")
+	cmds.register("download", middlewareLoggedIn(handlerDownload),
+		"gator download [enclosure id]... [--dir path] [--max-size mb]\ngator download --pending [--feed url] [--limit n] [--dir path] [--max-size mb]\n\tDownload enclosures by the ids shown in enclosures, or with --pending up to [n] (default 10) enclosures current user hasn't downloaded yet. Files are saved to [path], or download_dir from the config file (default ~/gator-downloads). Interrupted downloads resume where they stopped, and a download only counts once the size the server reported has been received. --max-size skips files larger than [mb] megabytes.")
+	cmds.register("import", middlewareLoggedIn(handlerImport),
+		"gator import [file.opml]\n\tFollow every feed listed in an OPML file for current user, adding feeds that are not tracked yet and remembering the folder each feed was in. Feeds in a folder whose name contains \"/\" are skipped, since \"/\" separates nested folders.")
+	cmds.register("export-opml", middlewareLoggedIn(handlerExportOPML),
+		"gator export-opml [file.opml]\n\tWrite current user's followed feeds as an OPML document to [file.opml], or to the terminal if no file is given. Folders from imported OPML files are kept.")
+	cmds.register("export-feed", middlewareLoggedIn(handlerExportFeed),
+		"gator export-feed [file] [--format atom|rss] [--limit n] [--url url]\n\tWrite current user's newest posts from every followed feed (default 50) as a single Atom or RSS 2.0 document to [file], or to the terminal if no file is given. --url sets the address the document will be published at.")
+	cmds.register("apikey", middlewareLoggedIn(handlerAPIKey),
+		"gator apikey [--rotate]\n\tShow current user's API key for gator serve. --rotate replaces it with a new key.")
+	cmds.register("serve", handlerServe,
+		"gator serve [address]\n\tServe a JSON REST API over the database at [address] (default :8080). Requests authenticate with an \"Authorization: ApiKey <key>\" header using a key from gator apikey.")
+	cmds.register("migrate", handlerMigrate,
+		"gator migrate [up|down|status|version]\n\tManage the database schema. up applies every pending migration, down rolls back the latest one, status lists each migration and whether it is applied and version prints the current schema version. Other commands refuse to run until the schema is up to date.")
+	cmds.register("prune", handlerPrune,
+		"gator prune [age]\n\tDelete posts published more than [age] ago, except posts starred by any user. [age] is formatted <number><unit>, e.g. 30d is 30 days. Each feed remembers the guids of its pruned posts, so agg won't save them again if the feed still lists them.")
 	cmd := command{}
 
 	if len(os.Args) < 2 {
@@ -100,6 +105,19 @@ func main() {
 		cmd.args = os.Args[2:]
 	}
 
+	// schema changes only happen through gator migrate
+	if cmd.name != "migrate" {
+		pending, err := migrations.HasPending(context.Background())
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if pending {
+			fmt.Println("database schema is out of date - run <gator migrate up> first")
+			os.Exit(1)
+		}
+	}
+
 	err = cmds.run(&s, cmd)
 	if err != nil {
 		fmt.Println(err)
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+func handlerMigrate(s *state, cmd command) error {
+	if len(cmd.args) != 1 {
+		return errors.New("expected 1 argument <up|down|status|version>")
+	}
+
+	ctx := context.Background()
+
+	switch cmd.args[0] {
+	case "up":
+		results, err := s.migrations.Up(ctx)
+		var partial *goose.PartialError
+		if errors.As(err, &partial) {
+			results = partial.Applied
+		}
+		for _, result := range results {
+			fmt.Printf("Applied %s (%v)\n", result.Source.Path, result.Duration.Round(time.Millisecond))
+		}
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Println("Database schema is already up to date")
+		}
+	case "down":
+		result, err := s.migrations.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			fmt.Println("No migrations to roll back")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %s (%v)\n", result.Source.Path, result.Duration.Round(time.Millisecond))
+	case "status":
+		statuses, err := s.migrations.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "MIGRATION\tSTATE\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := ""
+			if status.State == goose.StateApplied {
+				appliedAt = status.AppliedAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", status.Source.Path, status.State, appliedAt)
+		}
+		return tw.Flush()
+	case "version":
+		current, target, err := s.migrations.GetVersions(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Database schema version: %d (latest: %d)\n", current, target)
+	default:
+		return fmt.Errorf("unknown migrate action %q: expected up, down, status or version", cmd.args[0])
+	}
+
+	return nil
+}
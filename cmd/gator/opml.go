@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title       string `xml:"title"`
+		DateCreated string `xml:"dateCreated,omitempty"`
+	} `xml:"head"`
+	Body struct {
+		Outline []OPMLOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+type OPMLOutline struct {
+	Text    string        `xml:"text,attr"`
+	Title   string        `xml:"title,attr,omitempty"`
+	Type    string        `xml:"type,attr,omitempty"`
+	XMLURL  string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL string        `xml:"htmlUrl,attr,omitempty"`
+	Outline []OPMLOutline `xml:"outline"`
+}
+
+// opmlFeed is a subscription found in an OPML document, along with the
+// names of the folders it was nested in, outermost first
+type opmlFeed struct {
+	Name    string
+	URL     string
+	Folders []string
+}
+
+// opmlFeeds flattens nested outlines into the subscriptions they contain.
+// Outlines without an xmlUrl are folders.
+func opmlFeeds(outlines []OPMLOutline, folders []string) []opmlFeed {
+	var feeds []opmlFeed
+	for _, outline := range outlines {
+		name := strings.TrimSpace(outline.Title)
+		if name == "" {
+			name = strings.TrimSpace(outline.Text)
+		}
+
+		if url := strings.TrimSpace(outline.XMLURL); url != "" {
+			if name == "" {
+				name = url
+			}
+			feeds = append(feeds, opmlFeed{Name: name, URL: url, Folders: folders})
+		}
+
+		if len(outline.Outline) > 0 {
+			subfolders := folders
+			if outline.XMLURL == "" && name != "" {
+				subfolders = append(slices.Clip(folders), name)
+			}
+			feeds = append(feeds, opmlFeeds(outline.Outline, subfolders)...)
+		}
+	}
+	return feeds
+}
+
+// folderSeparator joins the names of nested folders in a follow's folder,
+// which export-opml splits on to nest the folders again
+const folderSeparator = "/"
+
+// joinFolders turns the names of nested folders into a follow's folder. A
+// name containing the separator is rejected, since it would be exported as
+// two folders.
+func joinFolders(names []string) (string, error) {
+	for _, name := range names {
+		if strings.Contains(name, folderSeparator) {
+			return "", fmt.Errorf("folder name %q contains %q, which separates nested folders", name, folderSeparator)
+		}
+	}
+	return strings.Join(names, folderSeparator), nil
+}
+
+// checkFolder rejects folders export-opml couldn't write out and import read
+// back the same: each name separated by "/" must be non-empty and not start
+// or end with spaces
+func checkFolder(folder string) error {
+	if folder == "" {
+		return nil
+	}
+	for _, name := range strings.Split(folder, folderSeparator) {
+		if name == "" || name != strings.TrimSpace(name) {
+			return fmt.Errorf("invalid folder %q: expected folder names separated by %q, without empty names or surrounding spaces", folder, folderSeparator)
+		}
+	}
+	return nil
+}
+
+func handlerImport(s *state, cmd command, user database.User) error {
+
+	if len(cmd.args) != 1 {
+		return errors.New("expected 1 argument <file.opml>")
+	}
+
+	file, err := os.Open(cmd.args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var opml OPML
+	if err := xml.NewDecoder(file).Decode(&opml); err != nil {
+		return fmt.Errorf("error decoding opml file: %w", err)
+	}
+
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	following := make(map[uuid.UUID]bool, len(follows))
+	for _, follow := range follows {
+		following[follow.FeedID] = true
+	}
+
+	var added, skipped, failed int
+	seen := make(map[string]bool)
+
+	for _, feed := range opmlFeeds(opml.Body.Outline, nil) {
+		if seen[feed.URL] {
+			fmt.Printf("Skipped %s: listed more than once\n", feed.URL)
+			skipped++
+			continue
+		}
+		seen[feed.URL] = true
+
+		status, err := importFeed(s, user, feed, following)
+		if err != nil {
+			fmt.Printf("Failed %s: %v\n", feed.URL, err)
+			failed++
+			continue
+		}
+		if status == "" {
+			fmt.Printf("Skipped %s: already following\n", feed.URL)
+			skipped++
+			continue
+		}
+		fmt.Printf("Added %s (%s)\n", feed.Name, status)
+		added++
+	}
+
+	fmt.Printf("Imported %d feeds: %d added, %d skipped, %d failed\n", added+skipped+failed, added, skipped, failed)
+
+	return nil
+}
+
+// importFeed creates a feed from an OPML document if it isn't tracked yet and
+// follows it for the user. It returns how the feed was added, or "" when the
+// user already follows it.
+func importFeed(s *state, user database.User, feed opmlFeed, following map[uuid.UUID]bool) (string, error) {
+	status := "followed existing feed"
+
+	folder, err := joinFolders(feed.Folders)
+	if err != nil {
+		return "", err
+	}
+
+	existing, err := s.db.GetFeedByURL(context.Background(), feed.URL)
+	if errors.Is(err, sql.ErrNoRows) {
+		feedParams := database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Name:      feed.Name,
+			Url:       feed.URL,
+			UserID:    user.ID,
+		}
+
+		existing, err = s.db.CreateFeed(context.Background(), feedParams)
+		if err != nil {
+			return "", err
+		}
+		status = "created feed"
+	} else if err != nil {
+		return "", err
+	}
+
+	if following[existing.ID] {
+		return "", nil
+	}
+
+	params := database.CreateFeedFollowParams{
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    existing.ID,
+		Folder:    folder,
+	}
+
+	if _, err := s.db.CreateFeedFollow(context.Background(), params); err != nil {
+		return "", err
+	}
+	following[existing.ID] = true
+
+	return status, nil
+}
+
+func handlerExportOPML(s *state, cmd command, user database.User) error {
+
+	if len(cmd.args) > 1 {
+		return errors.New("expected no more than 1 argument <file.opml> (default stdout)")
+	}
+
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(follows, func(i, j int) bool {
+		if follows[i].Folder != follows[j].Folder {
+			return follows[i].Folder < follows[j].Folder
+		}
+		return follows[i].FeedName < follows[j].FeedName
+	})
+
+	opml := OPML{Version: "2.0"}
+	opml.Head.Title = fmt.Sprintf("%s's subscriptions", user.Name)
+	opml.Head.DateCreated = time.Now().Format(time.RFC1123Z)
+
+	for _, follow := range follows {
+		outline := OPMLOutline{
+			Text:   follow.FeedName,
+			Title:  follow.FeedName,
+			Type:   "rss",
+			XMLURL: follow.FeedUrl,
+		}
+
+		var folders []string
+		if follow.Folder != "" {
+			folders = strings.Split(follow.Folder, folderSeparator)
+		}
+		addOPMLOutline(&opml.Body.Outline, folders, outline)
+	}
+
+	opmlXML, err := xml.MarshalIndent(opml, "", "  ")
+	if err != nil {
+		return err
+	}
+	opmlXML = append([]byte(xml.Header), opmlXML...)
+	opmlXML = append(opmlXML, '\n')
+
+	if len(cmd.args) == 0 {
+		_, err = os.Stdout.Write(opmlXML)
+		return err
+	}
+
+	err = os.WriteFile(cmd.args[0], opmlXML, 0644)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d feeds to %s\n", len(follows), cmd.args[0])
+
+	return nil
+}
+
+// addOPMLOutline adds a feed outline inside the nested folder outlines named
+// by folders, creating any folders that don't exist yet
+func addOPMLOutline(outlines *[]OPMLOutline, folders []string, feed OPMLOutline) {
+	if len(folders) == 0 {
+		*outlines = append(*outlines, feed)
+		return
+	}
+
+	for i := range *outlines {
+		folder := &(*outlines)[i]
+		if folder.XMLURL == "" && folder.Text == folders[0] {
+			addOPMLOutline(&folder.Outline, folders[1:], feed)
+			return
+		}
+	}
+
+	*outlines = append(*outlines, OPMLOutline{Text: folders[0]})
+	folder := &(*outlines)[len(*outlines)-1]
+	addOPMLOutline(&folder.Outline, folders[1:], feed)
+}
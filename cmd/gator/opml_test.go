@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOPMLFoldersRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	dir := t.TempDir()
+	imported := filepath.Join(dir, "imported.opml")
+	err := os.WriteFile(imported, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <body>
+    <outline text="Top" xmlUrl="https://example.com/top"/>
+    <outline text="News">
+      <outline text="Tech">
+        <outline text="Nested" xmlUrl="https://example.com/nested"/>
+      </outline>
+      <outline text="World" xmlUrl="https://example.com/world"/>
+    </outline>
+    <outline text="News/Tech">
+      <outline text="Slash" xmlUrl="https://example.com/slash"/>
+    </outline>
+  </body>
+</opml>
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"https://example.com/top":    "",
+		"https://example.com/nested": "News/Tech",
+		"https://example.com/world":  "News",
+	}
+	checkFolders := func(user string) {
+		t.Helper()
+		u, err := s.db.GetUserByName(ctx, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		follows, err := s.db.GetFeedFollowsForUser(ctx, u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(follows) != len(want) {
+			t.Errorf("%s follows %d feeds, want %d", user, len(follows), len(want))
+		}
+		for _, follow := range follows {
+			folder, ok := want[follow.FeedUrl]
+			if !ok {
+				t.Errorf("%s follows unexpected feed %s in %q", user, follow.FeedUrl, follow.Folder)
+			} else if follow.Folder != folder {
+				t.Errorf("%s follows %s in %q, want %q", user, follow.FeedUrl, follow.Folder, folder)
+			}
+		}
+	}
+
+	run(t, s, handlerRegister, "register", "alice")
+	run(t, s, middlewareLoggedIn(handlerImport), "import", imported)
+	checkFolders("alice")
+
+	// the folders alice exports come back the same when bob imports them
+	exported := filepath.Join(dir, "exported.opml")
+	run(t, s, middlewareLoggedIn(handlerExportOPML), "export-opml", exported)
+	run(t, s, handlerRegister, "register", "bob")
+	run(t, s, middlewareLoggedIn(handlerImport), "import", exported)
+	checkFolders("bob")
+}
+
+func TestCheckFolder(t *testing.T) {
+	tests := []struct {
+		folder string
+		ok     bool
+	}{
+		{"", true},
+		{"News", true},
+		{"News/Tech", true},
+		{"Tech News", true},
+		{"/News", false},
+		{"News/", false},
+		{"News//Tech", false},
+		{" News", false},
+		{"News / Tech", false},
+	}
+
+	for _, tt := range tests {
+		if err := checkFolder(tt.folder); (err == nil) != tt.ok {
+			t.Errorf("checkFolder(%q) = %v, want ok %v", tt.folder, err, tt.ok)
+		}
+	}
+}
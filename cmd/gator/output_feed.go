@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	outputFeedDefaultLimit = 50
+	outputFeedGenerator    = "gator"
+	outputFeedHomepage     = "https://github.com/Breadumi/aggreGator"
+)
+
+// outputAtomFeed and outputRSS are the documents gator publishes, as opposed
+// to AtomFeed and RSSFeed which only cover what it reads from other feeds
+type outputAtomFeed struct {
+	XMLName   xml.Name          `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string            `xml:"id"`
+	Title     string            `xml:"title"`
+	Updated   string            `xml:"updated"`
+	Generator string            `xml:"generator"`
+	Author    outputAtomPerson  `xml:"author"`
+	Link      []outputAtomLink  `xml:"link"`
+	Entry     []outputAtomEntry `xml:"entry"`
+}
+
+type outputAtomEntry struct {
+	ID        string           `xml:"id"`
+	Title     string           `xml:"title"`
+	Link      *outputAtomLink  `xml:"link,omitempty"`
+	Published string           `xml:"published"`
+	Updated   string           `xml:"updated"`
+	Author    outputAtomPerson `xml:"author"`
+	Summary   outputAtomText   `xml:"summary"`
+	Content   *outputAtomText  `xml:"content,omitempty"`
+}
+
+type outputAtomPerson struct {
+	Name string `xml:"name"`
+}
+
+type outputAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type outputAtomText struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type outputRSS struct {
+	XMLName   xml.Name `xml:"rss"`
+	Version   string   `xml:"version,attr"`
+	AtomXMLNS string   `xml:"xmlns:atom,attr,omitempty"`
+	Channel   struct {
+		Title         string          `xml:"title"`
+		Link          string          `xml:"link"`
+		Description   string          `xml:"description"`
+		LastBuildDate string          `xml:"lastBuildDate"`
+		Generator     string          `xml:"generator"`
+		AtomLink      *outputAtomLink `xml:"atom:link,omitempty"`
+		Item          []outputRSSItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type outputRSSItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link,omitempty"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	GUID        struct {
+		IsPermaLink string `xml:"isPermaLink,attr"`
+		Value       string `xml:",chardata"`
+	} `xml:"guid"`
+	Source struct {
+		URL  string `xml:"url,attr"`
+		Name string `xml:",chardata"`
+	} `xml:"source"`
+}
+
+// outputEntryID derives a post's ID in published feeds from its source feed
+// and guid, so it stays the same however often the post is saved or renumbered
+func outputEntryID(post database.GetPostsByUserRow) string {
+	return "urn:uuid:" + uuid.NewSHA1(post.FeedID, []byte(post.Guid)).String()
+}
+
+func outputEntryTitle(post database.GetPostsByUserRow) string {
+	if post.Title != "" {
+		return post.Title
+	}
+	return post.Url
+}
+
+// renderOutputFeed renders posts, newest first, as an Atom or RSS 2.0
+// document. selfURL is where the document is served, if known.
+func renderOutputFeed(format string, user database.User, posts []database.GetPostsByUserRow, selfURL string) ([]byte, error) {
+	title := fmt.Sprintf("%s's feeds", user.Name)
+
+	updated := time.Now()
+	if len(posts) > 0 {
+		updated = posts[0].PublishedAt
+	}
+
+	var doc interface{}
+	switch format {
+	case "atom":
+		feed := outputAtomFeed{
+			ID:        "urn:uuid:" + user.ID.String(),
+			Title:     title,
+			Updated:   updated.UTC().Format(time.RFC3339),
+			Generator: outputFeedGenerator,
+			Author:    outputAtomPerson{Name: user.Name},
+			Link:      []outputAtomLink{{Href: outputFeedHomepage, Rel: "alternate"}},
+		}
+		if selfURL != "" {
+			feed.Link = append(feed.Link, outputAtomLink{Href: selfURL, Rel: "self", Type: "application/atom+xml"})
+		}
+		for _, post := range posts {
+			published := post.PublishedAt.UTC().Format(time.RFC3339)
+			entry := outputAtomEntry{
+				ID:        outputEntryID(post),
+				Title:     outputEntryTitle(post),
+				Published: published,
+				Updated:   published,
+				Author:    outputAtomPerson{Name: post.FeedName},
+				Summary:   outputAtomText{Type: "html", Text: post.Description},
+			}
+			// posts from items without a link have no page to point to
+			if post.Url != "" {
+				entry.Link = &outputAtomLink{Href: post.Url, Rel: "alternate"}
+			}
+			if post.Author != "" {
+				entry.Author.Name = post.Author
+			}
+			if post.Content != "" {
+				entry.Content = &outputAtomText{Type: "html", Text: post.Content}
+			}
+			feed.Entry = append(feed.Entry, entry)
+		}
+		doc = feed
+	case "rss":
+		feed := outputRSS{Version: "2.0"}
+		feed.Channel.Title = title
+		feed.Channel.Link = outputFeedHomepage
+		feed.Channel.Description = fmt.Sprintf("Posts from every feed %s follows", user.Name)
+		feed.Channel.LastBuildDate = updated.Format(time.RFC1123Z)
+		feed.Channel.Generator = outputFeedGenerator
+		if selfURL != "" {
+			feed.AtomXMLNS = "http://www.w3.org/2005/Atom"
+			feed.Channel.AtomLink = &outputAtomLink{Href: selfURL, Rel: "self", Type: "application/rss+xml"}
+		}
+		for _, post := range posts {
+			item := outputRSSItem{
+				Title:       outputEntryTitle(post),
+				Link:        post.Url,
+				Description: post.Description,
+				PubDate:     post.PublishedAt.Format(time.RFC1123Z),
+			}
+			item.GUID.IsPermaLink = "false"
+			item.GUID.Value = outputEntryID(post)
+			item.Source.URL = post.FeedUrl
+			item.Source.Name = post.FeedName
+			feed.Channel.Item = append(feed.Channel.Item, item)
+		}
+		doc = feed
+	default:
+		return nil, fmt.Errorf("unknown feed format %q: expected atom or rss", format)
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	data = append([]byte(xml.Header), data...)
+	return append(data, '\n'), nil
+}
+
+func handlerExportFeed(s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("export-feed", flag.ContinueOnError)
+	format := fs.String("format", "atom", "document format, atom or rss")
+	limit := fs.Int("limit", outputFeedDefaultLimit, "number of posts to include")
+	selfURL := fs.String("url", "", "URL the document will be published at")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 1 {
+		return errors.New("expected no more than 1 argument <file> (default stdout)")
+	}
+	if *format != "atom" && *format != "rss" {
+		return fmt.Errorf("unknown --format %q: expected atom or rss", *format)
+	}
+	if *limit < 1 {
+		return errors.New("--limit must be a positive integer")
+	}
+
+	posts, err := s.db.GetPostsByUser(context.Background(), database.GetPostsByUserParams{
+		UserID: user.ID,
+		Limit:  int32(*limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := renderOutputFeed(*format, user, posts, *selfURL)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	err = os.WriteFile(args[0], data, 0644)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d posts to %s\n", len(posts), args[0])
+
+	return nil
+}
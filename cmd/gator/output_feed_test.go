@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestRenderOutputFeedOmitsMissingLinks(t *testing.T) {
+	user := database.User{ID: uuid.New(), Name: "alice"}
+	published := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	posts := []database.GetPostsByUserRow{
+		{Title: "Linked", Url: "https://example.com/linked", Guid: "linked", PublishedAt: published, FeedName: "Example"},
+		{Title: "Unlinked", Guid: "unlinked", PublishedAt: published, FeedName: "Example"},
+	}
+
+	tests := []struct {
+		format   string
+		linked   string
+		unlinked []string
+	}{
+		{"atom", `<link href="https://example.com/linked" rel="alternate"></link>`, []string{`href=""`}},
+		{"rss", `<link>https://example.com/linked</link>`, []string{`<link></link>`, `<link/>`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			data, err := renderOutputFeed(tt.format, user, posts, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc := string(data)
+
+			if strings.Count(doc, tt.linked) != 1 {
+				t.Errorf("linked post's link missing from:\n%s", doc)
+			}
+			for _, empty := range tt.unlinked {
+				if strings.Contains(doc, empty) {
+					t.Errorf("document contains an empty link %s:\n%s", empty, doc)
+				}
+			}
+
+			// the unlinked post is still published
+			rss, err := parseFeed("", data)
+			if err != nil {
+				t.Fatalf("parsing the rendered feed: %v", err)
+			}
+			if len(rss.Channel.Item) != 2 || rss.Channel.Item[1].Title != "Unlinked" || rss.Channel.Item[1].Link != "" {
+				t.Errorf("parsed items = %+v", rss.Channel.Item)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+)
+
+// descriptionLength is how many characters of a post's description are shown in listings
+const descriptionLength = 200
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// htmlBreakPattern matches the tags that start a new line when content is
+// shown as plain text
+var htmlBreakPattern = regexp.MustCompile(`(?i)<(br|/?p|/?div|/?li|/?h[1-6]|/?blockquote|/?pre)\b[^>]*>`)
+
+// postSummary holds the fields shown when listing posts
+type postSummary struct {
+	ID                  int32
+	Title               string
+	FeedName            string
+	Author              string
+	Url                 string
+	Description         string
+	PublishedAt         time.Time
+	PublishedAtInferred bool
+}
+
+func printPost(post postSummary) {
+	fmt.Printf("[%d] %s\n", post.ID, post.Title)
+	fmt.Printf("  Feed: %s\n", post.FeedName)
+	if post.Author != "" {
+		fmt.Printf("  Author: %s\n", post.Author)
+	}
+	if post.PublishedAtInferred {
+		fmt.Printf("  Published: %s (estimated)\n", post.PublishedAt.Format(time.DateTime))
+	} else {
+		fmt.Printf("  Published: %s\n", post.PublishedAt.Format(time.DateTime))
+	}
+	if post.Url != "" {
+		fmt.Printf("  URL: %s\n", post.Url)
+	}
+	if description := trimDescription(post.Description); description != "" {
+		fmt.Printf("  %s\n", description)
+	}
+	fmt.Println()
+}
+
+// trimDescription strips markup from a description and shortens it to descriptionLength characters
+func trimDescription(description string) string {
+	description = htmlTagPattern.ReplaceAllString(description, " ")
+	description = strings.Join(strings.Fields(description), " ")
+
+	runes := []rune(description)
+	if len(runes) <= descriptionLength {
+		return description
+	}
+	return strings.TrimSpace(string(runes[:descriptionLength])) + "..."
+}
+
+// printPostDetail shows everything stored about a post, including its full
+// content, which falls back to the description when the feed sent none
+func printPostDetail(post database.Post, feed database.Feed, categories []database.Category) {
+	fmt.Printf("[%d] %s\n", post.ID, post.Title)
+	fmt.Printf("Feed: %s\n", feed.Name)
+	if post.Author != "" {
+		fmt.Printf("Author: %s\n", post.Author)
+	}
+	if post.PublishedAtInferred {
+		fmt.Printf("Published: %s (estimated)\n", post.PublishedAt.Format(time.DateTime))
+	} else {
+		fmt.Printf("Published: %s\n", post.PublishedAt.Format(time.DateTime))
+	}
+	if post.Url != "" {
+		fmt.Printf("URL: %s\n", post.Url)
+	}
+	if len(categories) > 0 {
+		names := make([]string, 0, len(categories))
+		for _, category := range categories {
+			names = append(names, category.Name)
+		}
+		fmt.Printf("Categories: %s\n", strings.Join(names, ", "))
+	}
+
+	content := post.Content
+	if content == "" {
+		content = post.Description
+	}
+	if text := contentText(content); text != "" {
+		fmt.Printf("\n%s\n", text)
+	}
+}
+
+// contentText converts HTML content to plain text, keeping paragraph and line
+// breaks but dropping every other tag
+func contentText(content string) string {
+	content = htmlBreakPattern.ReplaceAllString(content, "\n")
+	content = htmlTagPattern.ReplaceAllString(content, " ")
+	content = html.UnescapeString(content)
+
+	var paragraphs []string
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			lines = append(lines, line)
+			continue
+		}
+		if len(lines) > 0 {
+			paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+			lines = nil
+		}
+	}
+	if len(lines) > 0 {
+		paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -1,67 +1,572 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
+// RSSFeed is an RSS 2.0 document. The ttl and skipHours polling hints are
+// kept as text since a malformed hint shouldn't stop the feed from parsing.
 type RSSFeed struct {
 	Channel struct {
 		Title       string    `xml:"title"`
 		Link        string    `xml:"link"`
 		Description string    `xml:"description"`
+		TTL         string    `xml:"ttl"`
+		SkipHours   []string  `xml:"skipHours>hour"`
+		SkipDays    []string  `xml:"skipDays>day"`
 		Item        []RSSItem `xml:"item"`
 	} `xml:"channel"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title        string         `xml:"title"`
+	Link         string         `xml:"link"`
+	Description  string         `xml:"description"`
+	Content      string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Creator      string         `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	ITunesAuthor string         `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+	Author       string         `xml:"author"`
+	Categories   []string       `xml:"category"`
+	Enclosures   []RSSEnclosure `xml:"enclosure"`
+	Duration     string         `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	PubDate      string         `xml:"pubDate"`
+	GUID         string         `xml:"guid"`
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+// RSSEnclosure is a media file attached to an item, such as a podcast
+// episode. Length is kept as text since feeds often leave it empty or invalid.
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type AtomFeed struct {
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Link     []AtomLink  `xml:"link"`
+	Entry    []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID        string         `xml:"id"`
+	Title     AtomText       `xml:"title"`
+	Link      []AtomLink     `xml:"link"`
+	Summary   AtomText       `xml:"summary"`
+	Content   AtomText       `xml:"content"`
+	Author    []AtomPerson   `xml:"author"`
+	Category  []AtomCategory `xml:"category"`
+	Published string         `xml:"published"`
+	Updated   string         `xml:"updated"`
+}
+
+// AtomText is an Atom text construct. Text holds its content for type="text"
+// and type="html", where HTML arrives escaped. For type="xhtml" the content
+// is markup inside a wrapping div, which only Inner keeps.
+type AtomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// html returns the construct's content, with XHTML unwrapped from its div
+func (t AtomText) html() string {
+	if t.Type != "xhtml" {
+		return t.Text
+	}
+
+	inner := strings.TrimSpace(t.Inner)
+	decoder := xml.NewDecoder(strings.NewReader(inner))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return inner
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "div" {
+			return inner
+		}
+		break
+	}
+
+	// the div's content runs from the end of its start tag to its end tag
+	contentStart := int(decoder.InputOffset())
+	contentEnd := strings.LastIndex(inner, "</")
+	if contentEnd < contentStart {
+		// an empty <div/>
+		return ""
+	}
+	return strings.TrimSpace(inner[contentStart:contentEnd])
+}
+
+// text returns the construct's content with any XHTML markup removed, for
+// titles
+func (t AtomText) text() string {
+	if t.Type != "xhtml" {
+		return t.Text
+	}
+
+	var text strings.Builder
+	decoder := xml.NewDecoder(strings.NewReader(t.Inner))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if data, ok := token.(xml.CharData); ok {
+			text.Write(data)
+		}
+	}
+	return strings.Join(strings.Fields(text.String()), " ")
+}
+
+type AtomPerson struct {
+	Name string `xml:"name"`
+}
+
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// RDFFeed is an RSS 1.0 document, where items are siblings of the channel
+// rather than children of it
+type RDFFeed struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+	} `xml:"channel"`
+	Item []RDFItem `xml:"item"`
+}
+
+type RDFItem struct {
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subject     []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+}
+
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            JSONFeedID           `json:"id"`
+	URL           string               `json:"url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Authors       []JSONFeedAuthor     `json:"authors"`
+	Author        *JSONFeedAuthor      `json:"author"`
+	Tags          []string             `json:"tags"`
+	Attachments   []JSONFeedAttachment `json:"attachments"`
+}
+
+// JSONFeedID is an item's id. The spec requires a string, but some feeds
+// publish numbers, which are kept as their decimal text.
+type JSONFeedID string
+
+func (id *JSONFeedID) UnmarshalJSON(data []byte) error {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case string:
+		*id = JSONFeedID(v)
+	case json.Number:
+		*id = JSONFeedID(v.String())
+	case nil:
+		*id = ""
+	default:
+		return fmt.Errorf("json feed item id must be a string or number, got %s", data)
+	}
+	return nil
+}
+
+type JSONFeedAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+// JSONFeedAuthor appears as the authors array in JSON Feed 1.1 and as a
+// single author object in 1.0
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// cacheHeaders holds the validators a server sent with a feed, which are
+// echoed back on the next request so unchanged feeds are not downloaded again
+type cacheHeaders struct {
+	ETag         string
+	LastModified string
+}
+
+var errNotModified = errors.New("feed not modified since last fetch")
+
+func fetchFeed(ctx context.Context, feedURL string, cache cacheHeaders) (*RSSFeed, cacheHeaders, error) {
 
 	client := http.Client{}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
-		return nil, err
+		return nil, cacheHeaders{}, err
 	}
 	req.Header.Set("User-Agent", "gator")
+	if cache.ETag != "" {
+		req.Header.Set("If-None-Match", cache.ETag)
+	}
+	if cache.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cache.LastModified)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, cacheHeaders{}, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, cache, errNotModified
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, cacheHeaders{}, fmt.Errorf("fetching %s: unexpected status %s", feedURL, resp.Status)
+	}
+
+	newCache := cacheHeaders{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+
 	rss_xml, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, cacheHeaders{}, err
 	}
-	fmt.Println("Raw XML:", string(rss_xml)) // temporary debug line
 
-	var rss RSSFeed
-	err = xml.Unmarshal(rss_xml, &rss)
+	rss, err := parseFeed(resp.Header.Get("Content-Type"), rss_xml)
 	if err != nil {
-		return nil, err
+		return nil, cacheHeaders{}, err
 	}
 
-	// clean the XML text for titles and descriptions
+	// clean the XML text for titles, descriptions, authors and categories.
+	// Content is kept as the HTML the feed sent.
 	rss.Channel.Title = cleanXML(rss.Channel.Title)
 	rss.Channel.Description = cleanXML(rss.Channel.Description)
 	for i := range rss.Channel.Item {
-		rss.Channel.Item[i].Title = cleanXML(rss.Channel.Item[i].Title)
-		rss.Channel.Item[i].Description = cleanXML(rss.Channel.Item[i].Description)
+		item := &rss.Channel.Item[i]
+		item.Title = cleanXML(item.Title)
+		item.Description = cleanXML(item.Description)
+		item.Creator = cleanXML(item.Creator)
+		item.ITunesAuthor = cleanXML(item.ITunesAuthor)
+		item.Author = cleanXML(item.Author)
+		for j := range item.Categories {
+			item.Categories[j] = cleanXML(item.Categories[j])
+		}
+	}
+
+	return rss, newCache, nil
+}
+
+// parseFeed detects the feed format from the Content-Type header or the
+// document itself and normalizes it into an RSSFeed
+func parseFeed(contentType string, data []byte) (*RSSFeed, error) {
+	if isJSONFeed(contentType, data) {
+		var feed JSONFeed
+		if err := json.Unmarshal(data, &feed); err != nil {
+			return nil, err
+		}
+		return feed.toRSS(), nil
+	}
+
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root {
+	case "rss":
+		var rss RSSFeed
+		if err := xml.Unmarshal(data, &rss); err != nil {
+			return nil, err
+		}
+		return &rss, nil
+	case "feed":
+		var atom AtomFeed
+		if err := xml.Unmarshal(data, &atom); err != nil {
+			return nil, err
+		}
+		return atom.toRSS(), nil
+	case "RDF":
+		var rdf RDFFeed
+		if err := xml.Unmarshal(data, &rdf); err != nil {
+			return nil, err
+		}
+		return rdf.toRSS(), nil
+	default:
+		return nil, errors.New("unsupported feed format: root element <" + root + ">")
+	}
+}
+
+// rootElement returns the local name of the first element in an XML document
+func rootElement(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+func (a *AtomFeed) toRSS() *RSSFeed {
+	var rss RSSFeed
+	rss.Channel.Title = a.Title
+	rss.Channel.Link = atomLink(a.Link)
+	rss.Channel.Description = a.Subtitle
+
+	for _, entry := range a.Entry {
+		item := RSSItem{
+			Title:       entry.Title.text(),
+			Link:        atomLink(entry.Link),
+			Description: entry.Summary.html(),
+			Content:     entry.Content.html(),
+			PubDate:     entry.Published,
+			GUID:        entry.ID,
+		}
+		if item.Description == "" {
+			item.Description = item.Content
+		}
+		if len(entry.Author) > 0 {
+			item.Creator = entry.Author[0].Name
+		}
+		for _, link := range entry.Link {
+			if link.Rel == "enclosure" {
+				item.Enclosures = append(item.Enclosures, RSSEnclosure{URL: link.Href, Length: link.Length, Type: link.Type})
+			}
+		}
+		for _, category := range entry.Category {
+			if category.Label != "" {
+				item.Categories = append(item.Categories, category.Label)
+			} else {
+				item.Categories = append(item.Categories, category.Term)
+			}
+		}
+		if item.PubDate == "" {
+			item.PubDate = entry.Updated
+		}
+		rss.Channel.Item = append(rss.Channel.Item, item)
+	}
+
+	return &rss
+}
+
+func (r *RDFFeed) toRSS() *RSSFeed {
+	var rss RSSFeed
+	rss.Channel.Title = r.Channel.Title
+	rss.Channel.Link = r.Channel.Link
+	rss.Channel.Description = r.Channel.Description
+
+	for _, rdfItem := range r.Item {
+		rss.Channel.Item = append(rss.Channel.Item, RSSItem{
+			Title:       rdfItem.Title,
+			Link:        rdfItem.Link,
+			Description: rdfItem.Description,
+			Content:     rdfItem.Content,
+			Creator:     rdfItem.Creator,
+			Categories:  rdfItem.Subject,
+			PubDate:     rdfItem.Date,
+			GUID:        rdfItem.About,
+		})
+	}
+
+	return &rss
+}
+
+func isJSONFeed(contentType string, data []byte) bool {
+	if strings.Contains(contentType, "json") {
+		return true
+	}
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+func (j *JSONFeed) toRSS() *RSSFeed {
+	var rss RSSFeed
+	rss.Channel.Title = j.Title
+	rss.Channel.Link = j.HomePageURL
+	rss.Channel.Description = j.Description
+
+	for _, jsonItem := range j.Items {
+		item := RSSItem{
+			Title:       jsonItem.Title,
+			Link:        jsonItem.URL,
+			Description: jsonItem.ContentHTML,
+			Content:     jsonItem.ContentHTML,
+			Categories:  jsonItem.Tags,
+			PubDate:     jsonItem.DatePublished,
+			GUID:        string(jsonItem.ID),
+		}
+		if item.Content == "" {
+			item.Content = html.EscapeString(jsonItem.ContentText)
+		}
+		if len(jsonItem.Authors) > 0 {
+			item.Creator = jsonItem.Authors[0].Name
+		} else if jsonItem.Author != nil {
+			item.Creator = jsonItem.Author.Name
+		}
+		for _, attachment := range jsonItem.Attachments {
+			item.Enclosures = append(item.Enclosures, RSSEnclosure{
+				URL:    attachment.URL,
+				Length: strconv.FormatInt(attachment.SizeInBytes, 10),
+				Type:   attachment.MimeType,
+			})
+			if item.Duration == "" && attachment.DurationInSeconds > 0 {
+				item.Duration = strconv.Itoa(int(attachment.DurationInSeconds))
+			}
+		}
+		if item.Description == "" {
+			item.Description = jsonItem.ContentText
+		}
+		if item.Description == "" {
+			item.Description = jsonItem.Summary
+		}
+		if item.PubDate == "" {
+			item.PubDate = jsonItem.DateModified
+		}
+		rss.Channel.Item = append(rss.Channel.Item, item)
 	}
 
-	return &rss, nil
+	return &rss
+}
+
+// atomLink picks the alternate link, which Atom treats as the default when rel is omitted
+func atomLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
+
+// identity returns the value posts are deduplicated on within a feed: the
+// item's GUID when the feed provides one, otherwise its link or title
+func (item RSSItem) identity() string {
+	for _, id := range []string{item.GUID, item.Link, item.Title} {
+		if id = strings.TrimSpace(id); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// authorName returns the item's author, preferring dc:creator and
+// itunes:author, which hold a name, over RSS's author element, which holds an
+// email address optionally followed by the name in parentheses
+func (item RSSItem) authorName() string {
+	if creator := strings.TrimSpace(item.Creator); creator != "" {
+		return creator
+	}
+	if author := strings.TrimSpace(item.ITunesAuthor); author != "" {
+		return author
+	}
+	author := strings.TrimSpace(item.Author)
+	if open := strings.Index(author, "("); open >= 0 && strings.HasSuffix(author, ")") {
+		if name := strings.TrimSpace(author[open+1 : len(author)-1]); name != "" {
+			return name
+		}
+	}
+	return author
+}
+
+// categoryNames returns the item's categories trimmed, with empty and
+// repeated names dropped
+func (item RSSItem) categoryNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, category := range item.Categories {
+		category = strings.TrimSpace(category)
+		if category == "" || seen[category] {
+			continue
+		}
+		seen[category] = true
+		names = append(names, category)
+	}
+	return names
+}
+
+// durationSeconds parses itunes:duration, which is either a number of seconds
+// or a time such as 1:02:03 or 62:03. Fractions of a second are dropped.
+func (item RSSItem) durationSeconds() sql.NullInt32 {
+	duration := strings.TrimSpace(item.Duration)
+	if duration == "" {
+		return sql.NullInt32{}
+	}
+
+	seconds := 0
+	for _, part := range strings.Split(duration, ":") {
+		part, _, _ = strings.Cut(part, ".")
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return sql.NullInt32{}
+		}
+		seconds = seconds*60 + n
+	}
+	return sql.NullInt32{Int32: int32(seconds), Valid: seconds > 0}
+}
+
+// sizeBytes parses the enclosure's length, treating zero as unknown since
+// feeds commonly use it as a placeholder
+func (enclosure RSSEnclosure) sizeBytes() sql.NullInt64 {
+	size, err := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64)
+	if err != nil || size <= 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: size, Valid: true}
 }
 
 func cleanXML(s string) string {
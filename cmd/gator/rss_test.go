@@ -0,0 +1,84 @@
+package main
+
+import "testing"
+
+func TestParseJSONFeedIDs(t *testing.T) {
+	data := `{
+		"version": "https://jsonfeed.org/version/1.1",
+		"title": "Example",
+		"items": [
+			{"id": "post-1", "title": "String"},
+			{"id": 42, "title": "Integer"},
+			{"id": 12345678901234567890, "title": "Large integer"},
+			{"id": null, "url": "https://example.com/4", "title": "Null"}
+		]
+	}`
+
+	rss, err := parseFeed("application/feed+json", []byte(data))
+	if err != nil {
+		t.Fatalf("parsing feed: %v", err)
+	}
+
+	want := []string{"post-1", "42", "12345678901234567890", "https://example.com/4"}
+	if len(rss.Channel.Item) != len(want) {
+		t.Fatalf("got %d items, want %d", len(rss.Channel.Item), len(want))
+	}
+	for i, item := range rss.Channel.Item {
+		if got := item.identity(); got != want[i] {
+			t.Errorf("item %d identity = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestParseAtomTextConstructs(t *testing.T) {
+	data := `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Example</title>
+	<entry>
+		<id>urn:1</id>
+		<title type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml">Fish <em>&amp;</em> chips</div></title>
+		<summary type="xhtml">
+			<div xmlns="http://www.w3.org/1999/xhtml"><p>Caf&#233; summary</p></div>
+		</summary>
+		<content type="xhtml"><xhtml:div xmlns:xhtml="http://www.w3.org/1999/xhtml"><p>Some <b>content</b></p></xhtml:div></content>
+	</entry>
+	<entry>
+		<id>urn:2</id>
+		<title type="html">Plain &lt;i&gt;title&lt;/i&gt;</title>
+		<content type="html"><![CDATA[<p>Escaped</p>]]></content>
+	</entry>
+	<entry>
+		<id>urn:3</id>
+		<title>Empty</title>
+		<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"/></content>
+	</entry>
+</feed>`
+
+	rss, err := parseFeed("application/atom+xml", []byte(data))
+	if err != nil {
+		t.Fatalf("parsing feed: %v", err)
+	}
+
+	tests := []struct {
+		title, description, content string
+	}{
+		{"Fish & chips", "<p>Caf&#233; summary</p>", "<p>Some <b>content</b></p>"},
+		{"Plain <i>title</i>", "<p>Escaped</p>", "<p>Escaped</p>"},
+		{"Empty", "", ""},
+	}
+	if len(rss.Channel.Item) != len(tests) {
+		t.Fatalf("got %d items, want %d", len(rss.Channel.Item), len(tests))
+	}
+	for i, tt := range tests {
+		item := rss.Channel.Item[i]
+		if item.Title != tt.title {
+			t.Errorf("item %d title = %q, want %q", i, item.Title, tt.title)
+		}
+		if item.Description != tt.description {
+			t.Errorf("item %d description = %q, want %q", i, item.Description, tt.description)
+		}
+		if item.Content != tt.content {
+			t.Errorf("item %d content = %q, want %q", i, item.Content, tt.content)
+		}
+	}
+}
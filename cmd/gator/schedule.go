@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+)
+
+// pollBounds limits how often and how rarely a feed is polled
+type pollBounds struct {
+	min time.Duration
+	max time.Duration
+}
+
+// scheduleHints are the polling hints an RSS channel can publish: <ttl> is the
+// number of minutes the feed may be cached, while <skipHours> and <skipDays>
+// name the GMT hours and weekdays the feed should not be polled at all
+type scheduleHints struct {
+	ttl       time.Duration
+	skipHours map[int]bool
+	skipDays  map[time.Weekday]bool
+}
+
+func hintsFromFeed(rss *RSSFeed) scheduleHints {
+	hints := scheduleHints{
+		skipHours: make(map[int]bool),
+		skipDays:  make(map[time.Weekday]bool),
+	}
+
+	// hints that aren't whole numbers in range are ignored
+	ttl, err := strconv.Atoi(strings.TrimSpace(rss.Channel.TTL))
+	if err == nil && ttl > 0 {
+		hints.ttl = time.Duration(ttl) * time.Minute
+	}
+	for _, text := range rss.Channel.SkipHours {
+		hour, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil || hour < 0 || hour > 24 {
+			continue
+		}
+		// hour 24 is sometimes used for midnight
+		hints.skipHours[hour%24] = true
+	}
+	for _, day := range rss.Channel.SkipDays {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if strings.EqualFold(strings.TrimSpace(day), weekday.String()) {
+				hints.skipDays[weekday] = true
+			}
+		}
+	}
+
+	return hints
+}
+
+func (h scheduleHints) skips(t time.Time) bool {
+	t = t.UTC()
+	return h.skipHours[t.Hour()] || h.skipDays[t.Weekday()]
+}
+
+// scheduleNextFetch sets when a feed claimed by this worker is next due, based
+// on how often it has published recently and any hints from its channel
+func scheduleNextFetch(ctx context.Context, s *state, feed database.Feed, hints scheduleHints, bounds pollBounds) error {
+	stats, err := s.db.GetFeedPostingStats(ctx, feed.ID)
+	if err != nil {
+		return err
+	}
+
+	next := nextFetchAt(time.Now(), stats, hints, bounds)
+
+	return s.db.SetFeedNextFetch(ctx, database.SetFeedNextFetchParams{
+		ID:           feed.ID,
+		NextFetchAt:  sql.NullTime{Time: next, Valid: true},
+		ClaimedUntil: feed.ClaimedUntil,
+	})
+}
+
+func nextFetchAt(now time.Time, stats database.GetFeedPostingStatsRow, hints scheduleHints, bounds pollBounds) time.Time {
+	// poll twice per average gap between recent posts so new posts show up
+	// promptly; feeds with too little history to judge are polled rarely
+	interval := bounds.max
+	if stats.PostCount > 1 {
+		averageGap := time.Duration(stats.SpanSeconds) * time.Second / time.Duration(stats.PostCount-1)
+		interval = averageGap / 2
+	}
+
+	interval = max(interval, bounds.min, hints.ttl)
+	interval = min(interval, bounds.max)
+
+	next := now.Add(interval)
+
+	// move past skipped hours and days, giving up after a week in case every slot is skipped
+	for i := 0; i < 24*7 && hints.skips(next); i++ {
+		next = next.Truncate(time.Hour).Add(time.Hour)
+	}
+
+	return next
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHintsFromFeedIgnoresMalformedHints(t *testing.T) {
+	tests := []struct {
+		name      string
+		channel   string
+		ttl       time.Duration
+		skipHours []int
+	}{
+		{
+			name:      "valid",
+			channel:   `<ttl>60</ttl><skipHours><hour>3</hour><hour>24</hour></skipHours>`,
+			ttl:       time.Hour,
+			skipHours: []int{0, 3},
+		},
+		{
+			name:      "malformed",
+			channel:   `<ttl>sixty</ttl><skipHours><hour>noon</hour><hour> 5 </hour><hour>25</hour></skipHours>`,
+			skipHours: []int{5},
+		},
+		{
+			name:    "empty",
+			channel: `<ttl></ttl><skipHours><hour></hour></skipHours>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := `<?xml version="1.0"?><rss version="2.0"><channel><title>Example</title>` +
+				tt.channel + `<item><title>Post</title></item></channel></rss>`
+			rss, err := parseFeed("application/rss+xml", []byte(data))
+			if err != nil {
+				t.Fatalf("parsing feed: %v", err)
+			}
+			if len(rss.Channel.Item) != 1 {
+				t.Errorf("got %d items, want 1", len(rss.Channel.Item))
+			}
+
+			hints := hintsFromFeed(rss)
+			if hints.ttl != tt.ttl {
+				t.Errorf("ttl = %v, want %v", hints.ttl, tt.ttl)
+			}
+			if len(hints.skipHours) != len(tt.skipHours) {
+				t.Errorf("skip hours = %v, want %v", hints.skipHours, tt.skipHours)
+			}
+			for _, hour := range tt.skipHours {
+				if !hints.skipHours[hour] {
+					t.Errorf("skip hours = %v, want %v", hints.skipHours, tt.skipHours)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+)
+
+func handlerServe(s *state, cmd command) error {
+	if len(cmd.args) > 1 {
+		return errors.New("expected no more than 1 argument <address> (default :8080)")
+	}
+
+	addr := ":8080"
+	if len(cmd.args) == 1 {
+		addr = cmd.args[0]
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/healthz", func(w http.ResponseWriter, r *http.Request) {
+		respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /v1/users", middlewareAPIKey(s, apiGetUsers))
+	mux.HandleFunc("GET /v1/users/me", middlewareAPIKey(s, apiGetCurrentUser))
+	mux.HandleFunc("GET /v1/feeds", middlewareAPIKey(s, apiGetFeeds))
+	mux.HandleFunc("POST /v1/feeds", middlewareAPIKey(s, apiCreateFeed))
+	mux.HandleFunc("POST /v1/feeds/read", middlewareAPIKey(s, apiMarkFeedRead))
+	mux.HandleFunc("GET /v1/feed_follows", middlewareAPIKey(s, apiGetFeedFollows))
+	mux.HandleFunc("POST /v1/feed_follows", middlewareAPIKey(s, apiCreateFeedFollow))
+	mux.HandleFunc("DELETE /v1/feed_follows", middlewareAPIKey(s, apiDeleteFeedFollow))
+	mux.HandleFunc("GET /v1/posts", middlewareAPIKey(s, apiGetPosts))
+	mux.HandleFunc("GET /v1/posts/{postID}", middlewareAPIKey(s, apiGetPost))
+	mux.HandleFunc("POST /v1/posts/{postID}/read", middlewareAPIKey(s, apiMarkPostRead))
+	mux.HandleFunc("GET /v1/feed.atom", allowAPIKeyQuery(middlewareAPIKey(s, apiGetOutputFeed("atom", "application/atom+xml; charset=utf-8"))))
+	mux.HandleFunc("GET /v1/feed.rss", allowAPIKeyQuery(middlewareAPIKey(s, apiGetOutputFeed("rss", "application/rss+xml; charset=utf-8"))))
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	fmt.Printf("Serving API on %s\n", addr)
+	fmt.Println("Press ctrl+C to stop serving")
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	fmt.Println("Server stopped")
+	return nil
+}
+
+// middlewareAPIKey authenticates a request by the user's API key, sent as
+// "Authorization: ApiKey <key>" or "Authorization: Bearer <key>"
+func middlewareAPIKey(s *state, handler func(s *state, w http.ResponseWriter, r *http.Request, user database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apiKey, err := getAPIKey(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		user, err := s.db.GetUserByAPIKey(r.Context(), apiKey)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusUnauthorized, "invalid API key")
+			return
+		}
+		if err != nil {
+			respondWithServerError(w, err)
+			return
+		}
+
+		handler(s, w, r, user)
+	}
+}
+
+// allowAPIKeyQuery also accepts the API key as an api_key query parameter,
+// for feed readers that can't send an Authorization header
+func allowAPIKeyQuery(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if key := r.URL.Query().Get("api_key"); key != "" && r.Header.Get("Authorization") == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "ApiKey "+key)
+		}
+		handler(w, r)
+	}
+}
+
+func getAPIKey(headers http.Header) (string, error) {
+	authorization := headers.Get("Authorization")
+	if authorization == "" {
+		return "", errors.New("missing Authorization header")
+	}
+
+	scheme, key, ok := strings.Cut(authorization, " ")
+	if !ok || (scheme != "ApiKey" && scheme != "Bearer") || strings.TrimSpace(key) == "" {
+		return "", errors.New("malformed Authorization header: expected ApiKey <key>")
+	}
+
+	return strings.TrimSpace(key), nil
+}
+
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("error marshalling JSON: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func respondWithError(w http.ResponseWriter, code int, msg string) {
+	respondWithJSON(w, code, map[string]string{"error": msg})
+}
+
+// respondWithServerError logs an unexpected error without exposing its details to the client
+func respondWithServerError(w http.ResponseWriter, err error) {
+	log.Println(err)
+	respondWithError(w, http.StatusInternalServerError, "internal server error")
+}
@@ -3,9 +3,11 @@ package main
 import (
 	"github.com/Breadumi/aggreGator/internal/config"
 	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/pressly/goose/v3"
 )
 
 type state struct {
-	db  *database.Queries
-	cfg *config.Config
+	db         database.Store
+	cfg        *config.Config
+	migrations *goose.Provider
 }
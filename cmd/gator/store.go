@@ -0,0 +1,58 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/Breadumi/aggreGator/internal/sqlitedb"
+	"github.com/Breadumi/aggreGator/sql/schema"
+	sqliteschema "github.com/Breadumi/aggreGator/sql/sqlite/schema"
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteOptions turns on foreign keys for cascading deletes, waits for locks
+// held by other agg workers instead of failing, and stores times in a format
+// SQLite's date functions understand
+const sqliteOptions = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+
+// openStore connects to the database in the config's db_url. A sqlite://
+// URL names a SQLite database file, e.g. sqlite:///home/me/gator.db or
+// sqlite://gator.db relative to the working directory. Anything else is a
+// Postgres connection string. The returned provider runs the migrations for
+// that database from the ones embedded in the binary, so schema changes
+// don't depend on the directory gator is run from.
+func openStore(dbURL string) (database.Store, *goose.Provider, error) {
+	if path, ok := strings.CutPrefix(dbURL, "sqlite://"); ok {
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+
+		db, err := sql.Open("sqlite", path+separator+sqliteOptions)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		migrations, err := goose.NewProvider(goose.DialectSQLite3, db, sqliteschema.FS)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return sqlitedb.NewStore(db), migrations, nil
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrations, err := goose.NewProvider(goose.DialectPostgres, db, schema.FS)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return database.NewStore(db), migrations, nil
+}
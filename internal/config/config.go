@@ -7,12 +7,14 @@ import (
 )
 
 const (
-	configFileName = "/.gatorconfig.json"
+	configFileName  = "/.gatorconfig.json"
+	downloadDirName = "/gator-downloads"
 )
 
 type Config struct {
 	DbURL           string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
+	DownloadDir     string `json:"download_dir,omitempty"`
 }
 
 func Read() (*Config, error) {
@@ -46,6 +48,21 @@ func (c *Config) SetUser(username string) error {
 	return nil
 }
 
+// GetDownloadDir returns the directory enclosures are downloaded into,
+// defaulting to gator-downloads in the home directory
+func (c *Config) GetDownloadDir() (string, error) {
+	if c.DownloadDir != "" {
+		return c.DownloadDir, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return homeDir + downloadDirName, nil
+}
+
 func getFilePath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
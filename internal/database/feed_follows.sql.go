@@ -14,17 +14,18 @@ import (
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (created_at, updated_at, user_id, feed_id)
+    INSERT INTO feed_follows (created_at, updated_at, user_id, feed_id, folder)
     VALUES (
         $1,
         $2,
         $3,
-        $4
+        $4,
+        $5
     )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, folder
 )
 SELECT 
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.folder,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM inserted_feed_follow
@@ -37,6 +38,7 @@ type CreateFeedFollowParams struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Folder    string
 }
 
 type CreateFeedFollowRow struct {
@@ -45,6 +47,7 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Folder    string
 	FeedName  string
 	UserName  string
 }
@@ -55,6 +58,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		arg.UpdatedAt,
 		arg.UserID,
 		arg.FeedID,
+		arg.Folder,
 	)
 	var i CreateFeedFollowRow
 	err := row.Scan(
@@ -63,6 +67,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.Folder,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -88,7 +93,7 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 }
 
 const getFeedFollows = `-- name: GetFeedFollows :many
-SELECT id, created_at, updated_at, user_id, feed_id FROM feed_follows
+SELECT id, created_at, updated_at, user_id, feed_id, folder FROM feed_follows
 `
 
 func (q *Queries) GetFeedFollows(ctx context.Context) ([]FeedFollow, error) {
@@ -106,6 +111,7 @@ func (q *Queries) GetFeedFollows(ctx context.Context) ([]FeedFollow, error) {
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.Folder,
 		); err != nil {
 			return nil, err
 		}
@@ -121,7 +127,16 @@ func (q *Queries) GetFeedFollows(ctx context.Context) ([]FeedFollow, error) {
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, users.name AS user_name, feeds.name AS feed_name
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder, users.name AS user_name, feeds.name AS feed_name, feeds.url AS feed_url,
+    (
+        SELECT COUNT(*) FROM posts
+        WHERE posts.feed_id = feed_follows.feed_id
+        AND NOT EXISTS (
+            SELECT 1 FROM user_post_state
+            WHERE user_post_state.user_id = feed_follows.user_id
+            AND user_post_state.post_id = posts.id
+        )
+    ) AS unread_count
 FROM feed_follows
 INNER JOIN users ON feed_follows.user_id = users.id
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
@@ -129,13 +144,16 @@ WHERE feed_follows.user_id = $1
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        int32
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	UserName  string
-	FeedName  string
+	ID          int32
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	Folder      string
+	UserName    string
+	FeedName    string
+	FeedUrl     string
+	UnreadCount int64
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -153,8 +171,11 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.Folder,
 			&i.UserName,
 			&i.FeedName,
+			&i.FeedUrl,
+			&i.UnreadCount,
 		); err != nil {
 			return nil, err
 		}
@@ -7,11 +7,69 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const claimNextFeed = `-- name: ClaimNextFeed :one
+UPDATE feeds
+SET updated_at = $1, last_fetched_at = $1, claimed_until = $2
+WHERE feeds.id = (
+    SELECT id FROM feeds
+    WHERE (feeds.claimed_until IS NULL OR feeds.claimed_until < $1)
+    AND ($3::text IS NOT NULL OR (
+        (feeds.backoff_until IS NULL OR feeds.backoff_until <= $1)
+        AND (feeds.next_fetch_at IS NULL OR feeds.next_fetch_at <= $1)
+    ))
+    AND ($3::text IS NULL OR feeds.url = $3)
+    AND ($4::uuid IS NULL OR feeds.id IN (
+        SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = $4
+    ))
+    ORDER BY feeds.next_fetch_at ASC NULLS FIRST, feeds.last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, consecutive_failures, last_error, last_succeeded_at, backoff_until, next_fetch_at
+`
+
+type ClaimNextFeedParams struct {
+	Now          time.Time
+	ClaimedUntil sql.NullTime
+	Url          sql.NullString
+	UserID       uuid.NullUUID
+}
+
+// A feed asked for by url is claimed whether or not it is due.
+func (q *Queries) ClaimNextFeed(ctx context.Context, arg ClaimNextFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, claimNextFeed,
+		arg.Now,
+		arg.ClaimedUntil,
+		arg.Url,
+		arg.UserID,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.BackoffUntil,
+		&i.NextFetchAt,
+	)
+	return i, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES (
@@ -22,7 +80,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, consecutive_failures, last_error, last_succeeded_at, backoff_until, next_fetch_at
 `
 
 type CreateFeedParams struct {
@@ -52,12 +110,48 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.BackoffUntil,
+		&i.NextFetchAt,
+	)
+	return i, err
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, consecutive_failures, last_error, last_succeeded_at, backoff_until, next_fetch_at FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.BackoffUntil,
+		&i.NextFetchAt,
 	)
 	return i, err
 }
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, consecutive_failures, last_error, last_succeeded_at, backoff_until, next_fetch_at FROM feeds
 WHERE url = $1
 `
 
@@ -72,12 +166,20 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.BackoffUntil,
+		&i.NextFetchAt,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, consecutive_failures, last_error, last_succeeded_at, backoff_until, next_fetch_at FROM feeds
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -97,6 +199,14 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ClaimedUntil,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastSucceededAt,
+			&i.BackoffUntil,
+			&i.NextFetchAt,
 		); err != nil {
 			return nil, err
 		}
@@ -111,39 +221,92 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
-ORDER BY feeds.last_fetched_at ASC NULLS FIRST
-LIMIT 1
+const markFeedFailed = `-- name: MarkFeedFailed :exec
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1, last_error = $2, backoff_until = $3
+WHERE feeds.id = $1 AND feeds.claimed_until = $4
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
+type MarkFeedFailedParams struct {
+	ID           uuid.UUID
+	LastError    sql.NullString
+	BackoffUntil sql.NullTime
+	ClaimedUntil sql.NullTime
+}
+
+func (q *Queries) MarkFeedFailed(ctx context.Context, arg MarkFeedFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFailed,
+		arg.ID,
+		arg.LastError,
+		arg.BackoffUntil,
+		arg.ClaimedUntil,
 	)
-	return i, err
+	return err
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :exec
+const markFeedSucceeded = `-- name: MarkFeedSucceeded :exec
 UPDATE feeds
-SET updated_at = $2, last_fetched_at = $2
+SET consecutive_failures = 0, last_error = NULL, last_succeeded_at = $2, backoff_until = NULL
+WHERE feeds.id = $1 AND feeds.claimed_until = $3
+`
+
+type MarkFeedSucceededParams struct {
+	ID              uuid.UUID
+	LastSucceededAt sql.NullTime
+	ClaimedUntil    sql.NullTime
+}
+
+func (q *Queries) MarkFeedSucceeded(ctx context.Context, arg MarkFeedSucceededParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedSucceeded, arg.ID, arg.LastSucceededAt, arg.ClaimedUntil)
+	return err
+}
+
+const releaseFeedClaim = `-- name: ReleaseFeedClaim :exec
+UPDATE feeds
+SET claimed_until = NULL
+WHERE feeds.id = $1 AND feeds.claimed_until = $2
+`
+
+type ReleaseFeedClaimParams struct {
+	ID           uuid.UUID
+	ClaimedUntil sql.NullTime
+}
+
+func (q *Queries) ReleaseFeedClaim(ctx context.Context, arg ReleaseFeedClaimParams) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedClaim, arg.ID, arg.ClaimedUntil)
+	return err
+}
+
+const setFeedNextFetch = `-- name: SetFeedNextFetch :exec
+UPDATE feeds
+SET next_fetch_at = $2
+WHERE feeds.id = $1 AND feeds.claimed_until = $3
+`
+
+type SetFeedNextFetchParams struct {
+	ID           uuid.UUID
+	NextFetchAt  sql.NullTime
+	ClaimedUntil sql.NullTime
+}
+
+func (q *Queries) SetFeedNextFetch(ctx context.Context, arg SetFeedNextFetchParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedNextFetch, arg.ID, arg.NextFetchAt, arg.ClaimedUntil)
+	return err
+}
+
+const updateFeedCacheHeaders = `-- name: UpdateFeedCacheHeaders :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3
 WHERE feeds.id = $1
 `
 
-type MarkFeedFetchedParams struct {
-	ID        uuid.UUID
-	UpdatedAt time.Time
+type UpdateFeedCacheHeadersParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
 }
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.ID, arg.UpdatedAt)
+func (q *Queries) UpdateFeedCacheHeaders(ctx context.Context, arg UpdateFeedCacheHeadersParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCacheHeaders, arg.ID, arg.Etag, arg.LastModified)
 	return err
 }
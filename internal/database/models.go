@@ -11,14 +11,44 @@ import (
 	"github.com/google/uuid"
 )
 
+type Category struct {
+	ID   int32
+	Name string
+}
+
+type Enclosure struct {
+	ID              int32
+	PostID          int32
+	Url             string
+	MimeType        string
+	SizeBytes       sql.NullInt64
+	DurationSeconds sql.NullInt32
+}
+
+type EnclosureDownload struct {
+	UserID       uuid.UUID
+	EnclosureID  int32
+	Path         string
+	SizeBytes    int64
+	DownloadedAt time.Time
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	ClaimedUntil        sql.NullTime
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	LastSucceededAt     sql.NullTime
+	BackoffUntil        sql.NullTime
+	NextFetchAt         sql.NullTime
 }
 
 type FeedFollow struct {
@@ -27,17 +57,43 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Folder    string
+}
+
+type LegacyGuidFeed struct {
+	FeedID uuid.UUID
 }
 
 type Post struct {
-	ID          int32
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID                  int32
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Title               string
+	Url                 string
+	Description         string
+	PublishedAt         time.Time
+	FeedID              uuid.UUID
+	PublishedAtInferred bool
+	Guid                string
+	SearchVector        interface{}
+	Content             string
+	Author              string
+}
+
+type PostCategory struct {
+	PostID     int32
+	CategoryID int32
+}
+
+type PrunedPost struct {
+	FeedID uuid.UUID
+	Guid   string
+}
+
+type StarredPost struct {
+	UserID    uuid.UUID
+	PostID    int32
+	CreatedAt time.Time
 }
 
 type User struct {
@@ -45,4 +101,11 @@ type User struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Name      string
+	ApiKey    string
+}
+
+type UserPostState struct {
+	UserID uuid.UUID
+	PostID int32
+	ReadAt time.Time
 }
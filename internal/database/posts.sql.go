@@ -7,28 +7,40 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES($1, $2, $3, $4, $5, $6, $7)
-ON CONFLICT (url) DO NOTHING
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+INSERT INTO posts (created_at, updated_at, title, url, description, published_at, feed_id, published_at_inferred, guid, content, author)
+SELECT
+    $1::timestamp, $2::timestamp, $3::text, $4::text, $5::text, $6::timestamp,
+    $7::uuid, $8::boolean, $9::text, $10::text, $11::text
+WHERE NOT EXISTS (
+    SELECT 1 FROM pruned_posts
+    WHERE pruned_posts.feed_id = $7 AND pruned_posts.guid = $9
+)
+ON CONFLICT (feed_id, guid) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, published_at_inferred, guid, search_vector, content, author
 `
 
 type CreatePostParams struct {
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Title               string
+	Url                 string
+	Description         string
+	PublishedAt         time.Time
+	FeedID              uuid.UUID
+	PublishedAtInferred bool
+	Guid                string
+	Content             string
+	Author              string
 }
 
+// Posts deleted by prune aren't saved again while their feed still lists them.
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
 	row := q.db.QueryRowContext(ctx, createPost,
 		arg.CreatedAt,
@@ -38,6 +50,10 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.PublishedAtInferred,
+		arg.Guid,
+		arg.Content,
+		arg.Author,
 	)
 	var i Post
 	err := row.Scan(
@@ -49,33 +65,179 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.PublishedAtInferred,
+		&i.Guid,
+		&i.SearchVector,
+		&i.Content,
+		&i.Author,
+	)
+	return i, err
+}
+
+const deleteLegacyGUIDFeed = `-- name: DeleteLegacyGUIDFeed :execrows
+DELETE FROM legacy_guid_feeds
+WHERE feed_id = $1
+`
+
+func (q *Queries) DeleteLegacyGUIDFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteLegacyGUIDFeed, feedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUnstarredPostsBefore = `-- name: DeleteUnstarredPostsBefore :execrows
+WITH deleted AS (
+    DELETE FROM posts
+    WHERE posts.published_at < $1
+    AND NOT EXISTS (
+        SELECT 1 FROM starred_posts
+        WHERE starred_posts.post_id = posts.id
+    )
+    RETURNING posts.feed_id, posts.guid
+)
+INSERT INTO pruned_posts (feed_id, guid)
+SELECT feed_id, guid FROM deleted
+ON CONFLICT (feed_id, guid) DO NOTHING
+`
+
+// Pruned posts leave their guid behind in pruned_posts, so scraping the feed
+// again doesn't bring them back.
+func (q *Queries) DeleteUnstarredPostsBefore(ctx context.Context, publishedAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUnstarredPostsBefore, publishedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFeedPostingStats = `-- name: GetFeedPostingStats :one
+SELECT
+    COUNT(*) AS post_count,
+    COALESCE(EXTRACT(EPOCH FROM MAX(published_at) - MIN(published_at)), 0)::bigint AS span_seconds
+FROM (
+    SELECT published_at FROM posts
+    WHERE posts.feed_id = $1
+    AND NOT posts.published_at_inferred
+    ORDER BY published_at DESC
+    LIMIT 10
+) AS recent_posts
+`
+
+type GetFeedPostingStatsRow struct {
+	PostCount   int64
+	SpanSeconds int64
+}
+
+func (q *Queries) GetFeedPostingStats(ctx context.Context, feedID uuid.UUID) (GetFeedPostingStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedPostingStats, feedID)
+	var i GetFeedPostingStatsRow
+	err := row.Scan(&i.PostCount, &i.SpanSeconds)
+	return i, err
+}
+
+const getPostByID = `-- name: GetPostByID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, published_at_inferred, guid, search_vector, content, author FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPostByID(ctx context.Context, id int32) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByID, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.PublishedAtInferred,
+		&i.Guid,
+		&i.SearchVector,
+		&i.Content,
+		&i.Author,
 	)
 	return i, err
 }
 
 const getPostsByUser = `-- name: GetPostsByUser :many
-SELECT id, created_at, updated_at, title, url, description, published_at, feed_id FROM posts
-WHERE feed_id IN (
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.published_at_inferred, posts.guid, posts.search_vector, posts.content, posts.author, feeds.name AS feed_name, feeds.url AS feed_url FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.feed_id IN (
     SELECT feed_id FROM feed_follows
     WHERE feed_follows.user_id = $1
 )
-LIMIT $2
+AND ($2::timestamp IS NULL OR posts.published_at >= $2)
+AND ($3::timestamp IS NULL OR posts.published_at < $3)
+AND ($4::text IS NULL OR feeds.url = $4)
+AND ($5::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_categories
+    INNER JOIN categories ON post_categories.category_id = categories.id
+    WHERE post_categories.post_id = posts.id
+    AND lower(categories.name) = lower($5)
+))
+AND ($6::text IS NULL OR lower(posts.author) = lower($6))
+AND (NOT $7::boolean OR NOT EXISTS (
+    SELECT 1 FROM user_post_state
+    WHERE user_post_state.user_id = $1
+    AND user_post_state.post_id = posts.id
+))
+ORDER BY posts.published_at DESC, posts.id DESC
+LIMIT $8 OFFSET $9
 `
 
 type GetPostsByUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID     uuid.UUID
+	Since      sql.NullTime
+	Until      sql.NullTime
+	FeedUrl    sql.NullString
+	Category   sql.NullString
+	Author     sql.NullString
+	UnreadOnly bool
+	Limit      int32
+	Offset     int32
+}
+
+type GetPostsByUserRow struct {
+	ID                  int32
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Title               string
+	Url                 string
+	Description         string
+	PublishedAt         time.Time
+	FeedID              uuid.UUID
+	PublishedAtInferred bool
+	Guid                string
+	SearchVector        interface{}
+	Content             string
+	Author              string
+	FeedName            string
+	FeedUrl             string
 }
 
-func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsByUser, arg.UserID, arg.Limit)
+func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams) ([]GetPostsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByUser,
+		arg.UserID,
+		arg.Since,
+		arg.Until,
+		arg.FeedUrl,
+		arg.Category,
+		arg.Author,
+		arg.UnreadOnly,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []GetPostsByUserRow
 	for rows.Next() {
-		var i Post
+		var i GetPostsByUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -85,6 +247,84 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.PublishedAtInferred,
+			&i.Guid,
+			&i.SearchVector,
+			&i.Content,
+			&i.Author,
+			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT
+    posts.id, posts.title, posts.url, posts.description, posts.published_at, posts.published_at_inferred,
+    feeds.name AS feed_name,
+    ts_rank(posts.search_vector, websearch_to_tsquery('english', $1)) AS rank
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.search_vector @@ websearch_to_tsquery('english', $1)
+AND ($2::boolean OR posts.feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = $3
+))
+ORDER BY rank DESC, posts.published_at DESC
+LIMIT $4
+`
+
+type SearchPostsParams struct {
+	Query    string
+	AllFeeds bool
+	UserID   uuid.UUID
+	Limit    int32
+}
+
+type SearchPostsRow struct {
+	ID                  int32
+	Title               string
+	Url                 string
+	Description         string
+	PublishedAt         time.Time
+	PublishedAtInferred bool
+	FeedName            string
+	Rank                float32
+}
+
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		arg.Query,
+		arg.AllFeeds,
+		arg.UserID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.PublishedAtInferred,
+			&i.FeedName,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
@@ -98,3 +338,30 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 	}
 	return items, nil
 }
+
+const updateLegacyPostGUID = `-- name: UpdateLegacyPostGUID :execrows
+UPDATE posts
+SET guid = $1
+WHERE posts.feed_id = $2
+AND posts.guid = $3
+AND posts.url = $3
+AND NOT EXISTS (
+    SELECT 1 FROM posts AS existing
+    WHERE existing.feed_id = $2
+    AND existing.guid = $1
+)
+`
+
+type UpdateLegacyPostGUIDParams struct {
+	Guid   string
+	FeedID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) UpdateLegacyPostGUID(ctx context.Context, arg UpdateLegacyPostGUIDParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateLegacyPostGUID, arg.Guid, arg.FeedID, arg.Url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
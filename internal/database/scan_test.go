@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// stubConnector serves every query with the same rows, shaped like the ones
+// Postgres returns, so the generated Scan calls run through database/sql
+// without a database server
+type stubConnector struct {
+	rows [][]driver.Value
+}
+
+func (c stubConnector) Connect(context.Context) (driver.Conn, error) { return stubConn(c), nil }
+func (c stubConnector) Driver() driver.Driver                        { return nil }
+
+type stubConn stubConnector
+
+func (c stubConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c stubConn) Close() error                        { return nil }
+func (c stubConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c stubConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &stubRows{rows: c.rows}, nil
+}
+
+type stubRows struct {
+	rows [][]driver.Value
+}
+
+func (r *stubRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *stubRows) Close() error { return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func stubQueries(rows ...[]driver.Value) *Queries {
+	return New(sql.OpenDB(stubConnector{rows: rows}))
+}
+
+var (
+	testTime   = time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	testFeedID = uuid.MustParse("6f1c9a52-2a8e-4d7b-9a57-0c7c1a9f3e11")
+)
+
+// postValues returns the columns of a posts row in table order
+func postValues() []driver.Value {
+	return []driver.Value{
+		int64(7), testTime, testTime, "Title", "https://example.com/1", "Description",
+		testTime, testFeedID.String(), false, "guid-1", "'titl':1", "<p>Content</p>", "Ann",
+	}
+}
+
+func TestGetPostsByUserScansRow(t *testing.T) {
+	q := stubQueries(append(postValues(), "Example", "https://example.com/feed"))
+
+	posts, err := q.GetPostsByUser(context.Background(), GetPostsByUserParams{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("got %d posts, want 1", len(posts))
+	}
+	post := posts[0]
+	if post.ID != 7 || post.FeedID != testFeedID || post.Content != "<p>Content</p>" || post.Author != "Ann" {
+		t.Errorf("post columns scanned wrong: %+v", post)
+	}
+	if post.FeedName != "Example" || post.FeedUrl != "https://example.com/feed" {
+		t.Errorf("feed columns scanned wrong: %+v", post)
+	}
+}
+
+func TestGetStarredPostsForUserScansRow(t *testing.T) {
+	q := stubQueries(append(postValues(), "Example", testTime))
+
+	posts, err := q.GetStarredPostsForUser(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("got %d posts, want 1", len(posts))
+	}
+	if post := posts[0]; post.Author != "Ann" || post.FeedName != "Example" || !post.StarredAt.Equal(testTime) {
+		t.Errorf("starred post scanned wrong: %+v", post)
+	}
+}
+
+func TestGetPostByIDScansRow(t *testing.T) {
+	q := stubQueries(postValues())
+
+	post, err := q.GetPostByID(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Guid != "guid-1" || post.Content != "<p>Content</p>" || post.Author != "Ann" {
+		t.Errorf("post scanned wrong: %+v", post)
+	}
+}
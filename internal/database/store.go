@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Store is a Querier that can also run a group of queries as one
+// transaction
+type Store interface {
+	Querier
+	// InTx calls fn with a Querier whose queries all run in one transaction,
+	// which is committed if fn returns nil and rolled back otherwise
+	InTx(ctx context.Context, fn func(Querier) error) error
+}
+
+// NewStore returns a Store running the Postgres queries on db
+func NewStore(db *sql.DB) Store {
+	return &sqlStore{Queries: New(db), db: db}
+}
+
+type sqlStore struct {
+	*Queries
+	db *sql.DB
+}
+
+func (s *sqlStore) InTx(ctx context.Context, fn func(Querier) error) error {
+	return RunInTx(ctx, s.db, func(tx *sql.Tx) error {
+		return fn(s.WithTx(tx))
+	})
+}
+
+// RunInTx calls fn in a transaction on db, committing it if fn returns nil
+// and rolling it back otherwise
+func RunInTx(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
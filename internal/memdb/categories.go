@@ -0,0 +1,90 @@
+package memdb
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+)
+
+func (q *Queries) AddPostCategory(ctx context.Context, arg database.AddPostCategoryParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, pc := range q.postCategories {
+		if pc.PostID == arg.PostID && pc.CategoryID == arg.CategoryID {
+			return nil
+		}
+	}
+	if q.postIndex(arg.PostID) < 0 {
+		return foreignKeyViolation("post_categories", "post_categories_post_id_fkey")
+	}
+	if q.categoryIndex(arg.CategoryID) < 0 {
+		return foreignKeyViolation("post_categories", "post_categories_category_id_fkey")
+	}
+
+	q.postCategories = append(q.postCategories, database.PostCategory{
+		PostID:     arg.PostID,
+		CategoryID: arg.CategoryID,
+	})
+	return nil
+}
+
+// CreateCategory returns the existing category when one already has the name,
+// as ON CONFLICT DO UPDATE does
+func (q *Queries) CreateCategory(ctx context.Context, name string) (database.Category, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, category := range q.categories {
+		if category.Name == name {
+			return category, nil
+		}
+	}
+
+	q.lastCategoryID++
+	category := database.Category{
+		ID:   q.lastCategoryID,
+		Name: name,
+	}
+	q.categories = append(q.categories, category)
+	return category, nil
+}
+
+func (q *Queries) GetCategoriesForPost(ctx context.Context, postID int32) ([]database.Category, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var items []database.Category
+	for _, pc := range q.postCategories {
+		if pc.PostID == postID {
+			items = append(items, q.categories[q.categoryIndex(pc.CategoryID)])
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Name < items[j].Name
+	})
+	return items, nil
+}
+
+func (q *Queries) categoryIndex(id int32) int {
+	for i, category := range q.categories {
+		if category.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasCategory reports whether the post has a category matching name, ignoring
+// case
+func (q *Queries) hasCategory(postID int32, name string) bool {
+	for _, pc := range q.postCategories {
+		if pc.PostID == postID && strings.EqualFold(q.categories[q.categoryIndex(pc.CategoryID)].Name, name) {
+			return true
+		}
+	}
+	return false
+}
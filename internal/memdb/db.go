@@ -0,0 +1,241 @@
+// Package memdb implements database.Querier in memory, so handlers can be
+// exercised without a database server. It enforces the same unique and
+// foreign key constraints as sql/schema, including cascading deletes, and
+// stores times the way a Postgres TIMESTAMP column does: the offset is
+// dropped, keeping the wall-clock time to the microsecond, and reads return
+// it tagged as UTC.
+package memdb
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+// ErrConstraint is wrapped by the errors returned for writes Postgres would
+// reject for violating a unique or foreign key constraint
+var ErrConstraint = errors.New("constraint violation")
+
+func New() *Queries {
+	return &Queries{}
+}
+
+type Queries struct {
+	mu sync.Mutex
+	tables
+}
+
+// tables holds each table as a slice in insertion order, which is the order
+// queries without an ORDER BY return rows in
+type tables struct {
+	users           []database.User
+	feeds           []database.Feed
+	feedFollows     []database.FeedFollow
+	posts           []database.Post
+	userPostStates  []database.UserPostState
+	starredPosts    []database.StarredPost
+	categories      []database.Category
+	postCategories  []database.PostCategory
+	prunedPosts     []database.PrunedPost
+	legacyGUIDFeeds []database.LegacyGuidFeed
+
+	enclosures         []database.Enclosure
+	enclosureDownloads []database.EnclosureDownload
+
+	lastFeedFollowID int32
+	lastPostID       int32
+	lastCategoryID   int32
+	lastEnclosureID  int32
+}
+
+var _ database.Store = (*Queries)(nil)
+
+// clone copies every table, so that writes to q don't change the copy
+func (t tables) clone() tables {
+	t.users = slices.Clone(t.users)
+	t.feeds = slices.Clone(t.feeds)
+	t.feedFollows = slices.Clone(t.feedFollows)
+	t.posts = slices.Clone(t.posts)
+	t.userPostStates = slices.Clone(t.userPostStates)
+	t.starredPosts = slices.Clone(t.starredPosts)
+	t.categories = slices.Clone(t.categories)
+	t.postCategories = slices.Clone(t.postCategories)
+	t.prunedPosts = slices.Clone(t.prunedPosts)
+	t.legacyGUIDFeeds = slices.Clone(t.legacyGUIDFeeds)
+	t.enclosures = slices.Clone(t.enclosures)
+	t.enclosureDownloads = slices.Clone(t.enclosureDownloads)
+	return t
+}
+
+// InTx runs fn against q and, if fn fails, puts every table back the way it
+// was. Unlike a real transaction it isn't isolated: other goroutines see fn's
+// writes straight away, and lose their own writes made while fn ran if it
+// fails.
+func (q *Queries) InTx(ctx context.Context, fn func(database.Querier) error) error {
+	q.mu.Lock()
+	saved := q.tables.clone()
+	q.mu.Unlock()
+
+	if err := fn(q); err != nil {
+		q.mu.Lock()
+		q.tables = saved
+		q.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func uniqueViolation(constraint string) error {
+	return fmt.Errorf("%w: duplicate key value violates unique constraint %q", ErrConstraint, constraint)
+}
+
+func foreignKeyViolation(table, constraint string) error {
+	return fmt.Errorf("%w: insert or update on table %q violates foreign key constraint %q", ErrConstraint, table, constraint)
+}
+
+// timestamp keeps t's wall-clock time and discards its offset, as Postgres
+// does when lib/pq sends a time with an offset to a TIMESTAMP column
+func timestamp(t time.Time) time.Time {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return wall.Truncate(time.Microsecond)
+}
+
+func nullTimestamp(t sql.NullTime) sql.NullTime {
+	if !t.Valid {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: timestamp(t.Time), Valid: true}
+}
+
+// newAPIKey stands in for the api_key column default
+func newAPIKey() string {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return hex.EncodeToString(key)
+}
+
+func (q *Queries) userIndex(id uuid.UUID) int {
+	for i, user := range q.users {
+		if user.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *Queries) feedIndex(id uuid.UUID) int {
+	for i, feed := range q.feeds {
+		if feed.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// claimedFeedIndex finds a feed only while it holds the given claim, like
+// feeds.claimed_until = $n, which never matches NULL
+func (q *Queries) claimedFeedIndex(id uuid.UUID, claimedUntil sql.NullTime) int {
+	i := q.feedIndex(id)
+	if i < 0 || !claimedUntil.Valid || !q.feeds[i].ClaimedUntil.Valid {
+		return -1
+	}
+	if !q.feeds[i].ClaimedUntil.Time.Equal(timestamp(claimedUntil.Time)) {
+		return -1
+	}
+	return i
+}
+
+func (q *Queries) postIndex(id int32) int {
+	for i, post := range q.posts {
+		if post.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// deleteUsers deletes the users remove returns true for, along with every row
+// that references them
+func (q *Queries) deleteUsers(remove func(database.User) bool) {
+	removed := make(map[uuid.UUID]bool)
+	q.users = filter(q.users, func(user database.User) bool {
+		if remove(user) {
+			removed[user.ID] = true
+			return false
+		}
+		return true
+	})
+
+	q.deleteFeeds(func(feed database.Feed) bool { return removed[feed.UserID] })
+	q.feedFollows = filter(q.feedFollows, func(follow database.FeedFollow) bool { return !removed[follow.UserID] })
+	q.userPostStates = filter(q.userPostStates, func(state database.UserPostState) bool { return !removed[state.UserID] })
+	q.starredPosts = filter(q.starredPosts, func(star database.StarredPost) bool { return !removed[star.UserID] })
+	q.enclosureDownloads = filter(q.enclosureDownloads, func(download database.EnclosureDownload) bool { return !removed[download.UserID] })
+}
+
+// deleteFeeds deletes the feeds remove returns true for, along with their
+// follows, posts, pruned guids and legacy guid flags
+func (q *Queries) deleteFeeds(remove func(database.Feed) bool) {
+	removed := make(map[uuid.UUID]bool)
+	q.feeds = filter(q.feeds, func(feed database.Feed) bool {
+		if remove(feed) {
+			removed[feed.ID] = true
+			return false
+		}
+		return true
+	})
+
+	q.feedFollows = filter(q.feedFollows, func(follow database.FeedFollow) bool { return !removed[follow.FeedID] })
+	q.prunedPosts = filter(q.prunedPosts, func(pruned database.PrunedPost) bool { return !removed[pruned.FeedID] })
+	q.legacyGUIDFeeds = filter(q.legacyGUIDFeeds, func(legacy database.LegacyGuidFeed) bool { return !removed[legacy.FeedID] })
+	q.deletePosts(func(post database.Post) bool { return removed[post.FeedID] })
+}
+
+// deletePosts deletes the posts remove returns true for, along with their
+// read states, stars, categories and enclosures, and returns how many posts were deleted
+func (q *Queries) deletePosts(remove func(database.Post) bool) int64 {
+	removed := make(map[int32]bool)
+	q.posts = filter(q.posts, func(post database.Post) bool {
+		if remove(post) {
+			removed[post.ID] = true
+			return false
+		}
+		return true
+	})
+
+	q.userPostStates = filter(q.userPostStates, func(state database.UserPostState) bool { return !removed[state.PostID] })
+	q.starredPosts = filter(q.starredPosts, func(star database.StarredPost) bool { return !removed[star.PostID] })
+	q.postCategories = filter(q.postCategories, func(pc database.PostCategory) bool { return !removed[pc.PostID] })
+
+	removedEnclosures := make(map[int32]bool)
+	q.enclosures = filter(q.enclosures, func(enclosure database.Enclosure) bool {
+		if removed[enclosure.PostID] {
+			removedEnclosures[enclosure.ID] = true
+			return false
+		}
+		return true
+	})
+	q.enclosureDownloads = filter(q.enclosureDownloads, func(download database.EnclosureDownload) bool { return !removedEnclosures[download.EnclosureID] })
+	return int64(len(removed))
+}
+
+// filter returns the rows keep returns true for, reusing rows' storage
+func filter[T any](rows []T, keep func(T) bool) []T {
+	kept := rows[:0]
+	for _, row := range rows {
+		if keep(row) {
+			kept = append(kept, row)
+		}
+	}
+	clear(rows[len(kept):])
+	return kept
+}
@@ -0,0 +1,17 @@
+package memdb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestampKeepsWallClock(t *testing.T) {
+	edt := time.FixedZone("EDT", -4*60*60)
+	got := timestamp(time.Date(2026, 5, 1, 10, 30, 0, 1500, edt))
+
+	// Postgres keeps 10:30 and drops the offset rather than converting to 14:30
+	want := time.Date(2026, 5, 1, 10, 30, 0, 1000, time.UTC)
+	if !got.Equal(want) || got.Location() != time.UTC {
+		t.Errorf("timestamp = %v, want %v", got, want)
+	}
+}
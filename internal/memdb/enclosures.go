@@ -0,0 +1,150 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+// CreateEnclosure does nothing when the post already has an enclosure with
+// the same url, as ON CONFLICT DO NOTHING does
+func (q *Queries) CreateEnclosure(ctx context.Context, arg database.CreateEnclosureParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, enclosure := range q.enclosures {
+		if enclosure.PostID == arg.PostID && enclosure.Url == arg.Url {
+			return nil
+		}
+	}
+	if q.postIndex(arg.PostID) < 0 {
+		return foreignKeyViolation("enclosures", "enclosures_post_id_fkey")
+	}
+
+	q.lastEnclosureID++
+	q.enclosures = append(q.enclosures, database.Enclosure{
+		ID:              q.lastEnclosureID,
+		PostID:          arg.PostID,
+		Url:             arg.Url,
+		MimeType:        arg.MimeType,
+		SizeBytes:       arg.SizeBytes,
+		DurationSeconds: arg.DurationSeconds,
+	})
+	return nil
+}
+
+func (q *Queries) GetEnclosureByID(ctx context.Context, id int32) (database.Enclosure, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.enclosureIndex(id)
+	if i < 0 {
+		return database.Enclosure{}, sql.ErrNoRows
+	}
+	return q.enclosures[i], nil
+}
+
+func (q *Queries) GetEnclosureDownload(ctx context.Context, arg database.GetEnclosureDownloadParams) (database.EnclosureDownload, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.enclosureDownloadIndex(arg.UserID, arg.EnclosureID)
+	if i < 0 {
+		return database.EnclosureDownload{}, sql.ErrNoRows
+	}
+	return q.enclosureDownloads[i], nil
+}
+
+func (q *Queries) GetEnclosuresByUser(ctx context.Context, arg database.GetEnclosuresByUserParams) ([]database.GetEnclosuresByUserRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var items []database.GetEnclosuresByUserRow
+	for _, enclosure := range q.enclosures {
+		post := q.posts[q.postIndex(enclosure.PostID)]
+		if !q.follows(arg.UserID, post.FeedID) {
+			continue
+		}
+		feed := q.feeds[q.feedIndex(post.FeedID)]
+		if arg.FeedUrl.Valid && feed.Url != arg.FeedUrl.String {
+			continue
+		}
+		item := database.GetEnclosuresByUserRow{
+			ID:              enclosure.ID,
+			PostID:          enclosure.PostID,
+			Url:             enclosure.Url,
+			MimeType:        enclosure.MimeType,
+			SizeBytes:       enclosure.SizeBytes,
+			DurationSeconds: enclosure.DurationSeconds,
+			PostTitle:       post.Title,
+			PublishedAt:     post.PublishedAt,
+			FeedName:        feed.Name,
+		}
+		if i := q.enclosureDownloadIndex(arg.UserID, enclosure.ID); i >= 0 {
+			if arg.PendingOnly {
+				continue
+			}
+			download := q.enclosureDownloads[i]
+			item.DownloadPath = sql.NullString{String: download.Path, Valid: true}
+			item.DownloadedAt = sql.NullTime{Time: download.DownloadedAt, Valid: true}
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].PublishedAt.Equal(items[j].PublishedAt) {
+			return items[i].PublishedAt.After(items[j].PublishedAt)
+		}
+		return items[i].ID < items[j].ID
+	})
+	return page(items, arg.Limit, 0), nil
+}
+
+// MarkEnclosureDownloaded replaces any earlier download of the enclosure by
+// the user, as ON CONFLICT DO UPDATE does
+func (q *Queries) MarkEnclosureDownloaded(ctx context.Context, arg database.MarkEnclosureDownloadedParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	download := database.EnclosureDownload{
+		UserID:       arg.UserID,
+		EnclosureID:  arg.EnclosureID,
+		Path:         arg.Path,
+		SizeBytes:    arg.SizeBytes,
+		DownloadedAt: timestamp(arg.DownloadedAt),
+	}
+	if i := q.enclosureDownloadIndex(arg.UserID, arg.EnclosureID); i >= 0 {
+		q.enclosureDownloads[i] = download
+		return nil
+	}
+	if q.userIndex(arg.UserID) < 0 {
+		return foreignKeyViolation("enclosure_downloads", "enclosure_downloads_user_id_fkey")
+	}
+	if q.enclosureIndex(arg.EnclosureID) < 0 {
+		return foreignKeyViolation("enclosure_downloads", "enclosure_downloads_enclosure_id_fkey")
+	}
+
+	q.enclosureDownloads = append(q.enclosureDownloads, download)
+	return nil
+}
+
+func (q *Queries) enclosureIndex(id int32) int {
+	for i, enclosure := range q.enclosures {
+		if enclosure.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *Queries) enclosureDownloadIndex(userID uuid.UUID, enclosureID int32) int {
+	for i, download := range q.enclosureDownloads {
+		if download.UserID == userID && download.EnclosureID == enclosureID {
+			return i
+		}
+	}
+	return -1
+}
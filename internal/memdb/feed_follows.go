@@ -0,0 +1,115 @@
+package memdb
+
+import (
+	"context"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+func (q *Queries) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, follow := range q.feedFollows {
+		if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+			return database.CreateFeedFollowRow{}, uniqueViolation("feed_follows_user_id_feed_id_key")
+		}
+	}
+	userIndex := q.userIndex(arg.UserID)
+	if userIndex < 0 {
+		return database.CreateFeedFollowRow{}, foreignKeyViolation("feed_follows", "feed_follows_user_id_fkey")
+	}
+	feedIndex := q.feedIndex(arg.FeedID)
+	if feedIndex < 0 {
+		return database.CreateFeedFollowRow{}, foreignKeyViolation("feed_follows", "feed_follows_feed_id_fkey")
+	}
+
+	q.lastFeedFollowID++
+	follow := database.FeedFollow{
+		ID:        q.lastFeedFollowID,
+		CreatedAt: timestamp(arg.CreatedAt),
+		UpdatedAt: timestamp(arg.UpdatedAt),
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		Folder:    arg.Folder,
+	}
+	q.feedFollows = append(q.feedFollows, follow)
+
+	return database.CreateFeedFollowRow{
+		ID:        follow.ID,
+		CreatedAt: follow.CreatedAt,
+		UpdatedAt: follow.UpdatedAt,
+		UserID:    follow.UserID,
+		FeedID:    follow.FeedID,
+		Folder:    follow.Folder,
+		FeedName:  q.feeds[feedIndex].Name,
+		UserName:  q.users[userIndex].Name,
+	}, nil
+}
+
+func (q *Queries) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.feedFollows = filter(q.feedFollows, func(follow database.FeedFollow) bool {
+		if follow.UserID != arg.UserID {
+			return true
+		}
+		i := q.feedIndex(follow.FeedID)
+		return i < 0 || q.feeds[i].Url != arg.Url
+	})
+	return nil
+}
+
+func (q *Queries) GetFeedFollows(ctx context.Context) ([]database.FeedFollow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return append([]database.FeedFollow(nil), q.feedFollows...), nil
+}
+
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var items []database.GetFeedFollowsForUserRow
+	for _, follow := range q.feedFollows {
+		if follow.UserID != userID {
+			continue
+		}
+		user := q.users[q.userIndex(follow.UserID)]
+		feed := q.feeds[q.feedIndex(follow.FeedID)]
+
+		var unread int64
+		for _, post := range q.posts {
+			if post.FeedID == follow.FeedID && !q.isRead(userID, post.ID) {
+				unread++
+			}
+		}
+
+		items = append(items, database.GetFeedFollowsForUserRow{
+			ID:          follow.ID,
+			CreatedAt:   follow.CreatedAt,
+			UpdatedAt:   follow.UpdatedAt,
+			UserID:      follow.UserID,
+			FeedID:      follow.FeedID,
+			Folder:      follow.Folder,
+			UserName:    user.Name,
+			FeedName:    feed.Name,
+			FeedUrl:     feed.Url,
+			UnreadCount: unread,
+		})
+	}
+	return items, nil
+}
+
+// follows reports whether the user follows the feed
+func (q *Queries) follows(userID, feedID uuid.UUID) bool {
+	for _, follow := range q.feedFollows {
+		if follow.UserID == userID && follow.FeedID == feedID {
+			return true
+		}
+	}
+	return false
+}
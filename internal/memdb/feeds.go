@@ -0,0 +1,189 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+func (q *Queries) ClaimNextFeed(ctx context.Context, arg database.ClaimNextFeedParams) (database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := timestamp(arg.Now)
+
+	followed := make(map[uuid.UUID]bool)
+	if arg.UserID.Valid {
+		for _, follow := range q.feedFollows {
+			if follow.UserID == arg.UserID.UUID {
+				followed[follow.FeedID] = true
+			}
+		}
+	}
+
+	var due []int
+	for i, feed := range q.feeds {
+		if feed.ClaimedUntil.Valid && !feed.ClaimedUntil.Time.Before(now) {
+			continue
+		}
+		// a feed asked for by url is claimed whether or not it is due
+		if !arg.Url.Valid && feed.BackoffUntil.Valid && feed.BackoffUntil.Time.After(now) {
+			continue
+		}
+		if !arg.Url.Valid && feed.NextFetchAt.Valid && feed.NextFetchAt.Time.After(now) {
+			continue
+		}
+		if arg.Url.Valid && feed.Url != arg.Url.String {
+			continue
+		}
+		if arg.UserID.Valid && !followed[feed.ID] {
+			continue
+		}
+		due = append(due, i)
+	}
+	if len(due) == 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+
+	sort.SliceStable(due, func(a, b int) bool {
+		feedA, feedB := q.feeds[due[a]], q.feeds[due[b]]
+		if nullsFirstBefore(feedA.NextFetchAt, feedB.NextFetchAt) {
+			return true
+		}
+		if nullsFirstBefore(feedB.NextFetchAt, feedA.NextFetchAt) {
+			return false
+		}
+		return nullsFirstBefore(feedA.LastFetchedAt, feedB.LastFetchedAt)
+	})
+
+	feed := &q.feeds[due[0]]
+	feed.UpdatedAt = now
+	feed.LastFetchedAt = sql.NullTime{Time: now, Valid: true}
+	feed.ClaimedUntil = nullTimestamp(arg.ClaimedUntil)
+	return *feed, nil
+}
+
+// nullsFirstBefore orders times like ORDER BY ... ASC NULLS FIRST
+func nullsFirstBefore(a, b sql.NullTime) bool {
+	if !a.Valid || !b.Valid {
+		return !a.Valid && b.Valid
+	}
+	return a.Time.Before(b.Time)
+}
+
+func (q *Queries) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, feed := range q.feeds {
+		if feed.ID == arg.ID {
+			return database.Feed{}, uniqueViolation("feeds_pkey")
+		}
+		if feed.Url == arg.Url {
+			return database.Feed{}, uniqueViolation("feeds_url_key")
+		}
+	}
+	if q.userIndex(arg.UserID) < 0 {
+		return database.Feed{}, foreignKeyViolation("feeds", "feeds_user_id_fkey")
+	}
+
+	feed := database.Feed{
+		ID:        arg.ID,
+		CreatedAt: timestamp(arg.CreatedAt),
+		UpdatedAt: timestamp(arg.UpdatedAt),
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	}
+	q.feeds = append(q.feeds, feed)
+	return feed, nil
+}
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.feedIndex(id)
+	if i < 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return q.feeds[i], nil
+}
+
+func (q *Queries) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, feed := range q.feeds {
+		if feed.Url == url {
+			return feed, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (q *Queries) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return append([]database.Feed(nil), q.feeds...), nil
+}
+
+func (q *Queries) MarkFeedFailed(ctx context.Context, arg database.MarkFeedFailedParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if i := q.claimedFeedIndex(arg.ID, arg.ClaimedUntil); i >= 0 {
+		q.feeds[i].ConsecutiveFailures++
+		q.feeds[i].LastError = arg.LastError
+		q.feeds[i].BackoffUntil = nullTimestamp(arg.BackoffUntil)
+	}
+	return nil
+}
+
+func (q *Queries) MarkFeedSucceeded(ctx context.Context, arg database.MarkFeedSucceededParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if i := q.claimedFeedIndex(arg.ID, arg.ClaimedUntil); i >= 0 {
+		q.feeds[i].ConsecutiveFailures = 0
+		q.feeds[i].LastError = sql.NullString{}
+		q.feeds[i].LastSucceededAt = nullTimestamp(arg.LastSucceededAt)
+		q.feeds[i].BackoffUntil = sql.NullTime{}
+	}
+	return nil
+}
+
+func (q *Queries) ReleaseFeedClaim(ctx context.Context, arg database.ReleaseFeedClaimParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if i := q.claimedFeedIndex(arg.ID, arg.ClaimedUntil); i >= 0 {
+		q.feeds[i].ClaimedUntil = sql.NullTime{}
+	}
+	return nil
+}
+
+func (q *Queries) SetFeedNextFetch(ctx context.Context, arg database.SetFeedNextFetchParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if i := q.claimedFeedIndex(arg.ID, arg.ClaimedUntil); i >= 0 {
+		q.feeds[i].NextFetchAt = nullTimestamp(arg.NextFetchAt)
+	}
+	return nil
+}
+
+func (q *Queries) UpdateFeedCacheHeaders(ctx context.Context, arg database.UpdateFeedCacheHeadersParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if i := q.feedIndex(arg.ID); i >= 0 {
+		q.feeds[i].Etag = arg.Etag
+		q.feeds[i].LastModified = arg.LastModified
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/Breadumi/aggreGator/internal/search"
+	"github.com/google/uuid"
+)
+
+// CreatePost returns sql.ErrNoRows when the feed already has a post with the
+// same guid, as ON CONFLICT DO NOTHING does, or when the guid was pruned
+func (q *Queries) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.isPruned(arg.FeedID, arg.Guid) {
+		return database.Post{}, sql.ErrNoRows
+	}
+	for _, post := range q.posts {
+		if post.FeedID == arg.FeedID && post.Guid == arg.Guid {
+			return database.Post{}, sql.ErrNoRows
+		}
+	}
+	if q.feedIndex(arg.FeedID) < 0 {
+		return database.Post{}, foreignKeyViolation("posts", "posts_feed_id_fkey")
+	}
+
+	q.lastPostID++
+	post := database.Post{
+		ID:                  q.lastPostID,
+		CreatedAt:           timestamp(arg.CreatedAt),
+		UpdatedAt:           timestamp(arg.UpdatedAt),
+		Title:               arg.Title,
+		Url:                 arg.Url,
+		Description:         arg.Description,
+		PublishedAt:         timestamp(arg.PublishedAt),
+		FeedID:              arg.FeedID,
+		PublishedAtInferred: arg.PublishedAtInferred,
+		Guid:                arg.Guid,
+		Content:             arg.Content,
+		Author:              arg.Author,
+	}
+	q.posts = append(q.posts, post)
+	return post, nil
+}
+
+// AddLegacyGUIDFeed flags a feed as having posts whose guid is still their
+// url, standing in for the legacy_guid_feeds rows migration 008 adds
+func (q *Queries) AddLegacyGUIDFeed(feedID uuid.UUID) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, legacy := range q.legacyGUIDFeeds {
+		if legacy.FeedID == feedID {
+			return
+		}
+	}
+	q.legacyGUIDFeeds = append(q.legacyGUIDFeeds, database.LegacyGuidFeed{FeedID: feedID})
+}
+
+func (q *Queries) DeleteLegacyGUIDFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	before := len(q.legacyGUIDFeeds)
+	q.legacyGUIDFeeds = filter(q.legacyGUIDFeeds, func(legacy database.LegacyGuidFeed) bool { return legacy.FeedID != feedID })
+	return int64(before - len(q.legacyGUIDFeeds)), nil
+}
+
+func (q *Queries) DeleteUnstarredPostsBefore(ctx context.Context, publishedAt time.Time) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	publishedAt = timestamp(publishedAt)
+	return q.deletePosts(func(post database.Post) bool {
+		if !post.PublishedAt.Before(publishedAt) || q.isStarred(post.ID) {
+			return false
+		}
+		if !q.isPruned(post.FeedID, post.Guid) {
+			q.prunedPosts = append(q.prunedPosts, database.PrunedPost{FeedID: post.FeedID, Guid: post.Guid})
+		}
+		return true
+	}), nil
+}
+
+// GetFeedPostingStats covers the feed's 10 most recent posts with known
+// publication dates
+func (q *Queries) GetFeedPostingStats(ctx context.Context, feedID uuid.UUID) (database.GetFeedPostingStatsRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var published []time.Time
+	for _, post := range q.posts {
+		if post.FeedID == feedID && !post.PublishedAtInferred {
+			published = append(published, post.PublishedAt)
+		}
+	}
+	sort.Slice(published, func(i, j int) bool {
+		return published[i].After(published[j])
+	})
+	if len(published) > 10 {
+		published = published[:10]
+	}
+
+	var i database.GetFeedPostingStatsRow
+	i.PostCount = int64(len(published))
+	if len(published) > 0 {
+		i.SpanSeconds = int64(published[0].Sub(published[len(published)-1]).Seconds())
+	}
+	return i, nil
+}
+
+func (q *Queries) GetPostByID(ctx context.Context, id int32) (database.Post, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.postIndex(id)
+	if i < 0 {
+		return database.Post{}, sql.ErrNoRows
+	}
+	return q.posts[i], nil
+}
+
+func (q *Queries) GetPostsByUser(ctx context.Context, arg database.GetPostsByUserParams) ([]database.GetPostsByUserRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	since, until := nullTimestamp(arg.Since), nullTimestamp(arg.Until)
+
+	var items []database.GetPostsByUserRow
+	for _, post := range q.posts {
+		if !q.follows(arg.UserID, post.FeedID) {
+			continue
+		}
+		if since.Valid && post.PublishedAt.Before(since.Time) {
+			continue
+		}
+		if until.Valid && !post.PublishedAt.Before(until.Time) {
+			continue
+		}
+		feed := q.feeds[q.feedIndex(post.FeedID)]
+		if arg.FeedUrl.Valid && feed.Url != arg.FeedUrl.String {
+			continue
+		}
+		if arg.Category.Valid && !q.hasCategory(post.ID, arg.Category.String) {
+			continue
+		}
+		if arg.Author.Valid && !strings.EqualFold(post.Author, arg.Author.String) {
+			continue
+		}
+		if arg.UnreadOnly && q.isRead(arg.UserID, post.ID) {
+			continue
+		}
+
+		items = append(items, database.GetPostsByUserRow{
+			ID:                  post.ID,
+			CreatedAt:           post.CreatedAt,
+			UpdatedAt:           post.UpdatedAt,
+			Title:               post.Title,
+			Url:                 post.Url,
+			Description:         post.Description,
+			PublishedAt:         post.PublishedAt,
+			FeedID:              post.FeedID,
+			PublishedAtInferred: post.PublishedAtInferred,
+			Guid:                post.Guid,
+			Content:             post.Content,
+			Author:              post.Author,
+			FeedName:            feed.Name,
+			FeedUrl:             feed.Url,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].PublishedAt.Equal(items[j].PublishedAt) {
+			return items[i].PublishedAt.After(items[j].PublishedAt)
+		}
+		return items[i].ID > items[j].ID
+	})
+	return page(items, arg.Limit, arg.Offset), nil
+}
+
+func (q *Queries) isPruned(feedID uuid.UUID, guid string) bool {
+	for _, pruned := range q.prunedPosts {
+		if pruned.FeedID == feedID && pruned.Guid == guid {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *Queries) SearchPosts(ctx context.Context, arg database.SearchPostsParams) ([]database.SearchPostsRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	query := search.Parse(arg.Query, searchWords)
+
+	var items []database.SearchPostsRow
+	for _, post := range q.posts {
+		if !arg.AllFeeds && !q.follows(arg.UserID, post.FeedID) {
+			continue
+		}
+		rank, ok := rankPost(query, post.Title, post.Description)
+		if !ok {
+			continue
+		}
+
+		items = append(items, database.SearchPostsRow{
+			ID:                  post.ID,
+			Title:               post.Title,
+			Url:                 post.Url,
+			Description:         post.Description,
+			PublishedAt:         post.PublishedAt,
+			PublishedAtInferred: post.PublishedAtInferred,
+			FeedName:            q.feeds[q.feedIndex(post.FeedID)].Name,
+			Rank:                rank,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Rank != items[j].Rank {
+			return items[i].Rank > items[j].Rank
+		}
+		return items[i].PublishedAt.After(items[j].PublishedAt)
+	})
+	return page(items, arg.Limit, 0), nil
+}
+
+// UpdateLegacyPostGUID gives a post whose guid is still its url the item's
+// real guid, unless another post in the feed already has it
+func (q *Queries) UpdateLegacyPostGUID(ctx context.Context, arg database.UpdateLegacyPostGUIDParams) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, post := range q.posts {
+		if post.FeedID == arg.FeedID && post.Guid == arg.Guid {
+			return 0, nil
+		}
+	}
+
+	var updated int64
+	for i, post := range q.posts {
+		if post.FeedID == arg.FeedID && post.Guid == arg.Url && post.Url == arg.Url {
+			q.posts[i].Guid = arg.Guid
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// page applies LIMIT and OFFSET to sorted rows
+func page[T any](rows []T, limit, offset int32) []T {
+	if int(offset) >= len(rows) {
+		return nil
+	}
+	rows = rows[offset:]
+	if int(limit) < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
@@ -0,0 +1,66 @@
+package memdb
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/Breadumi/aggreGator/internal/search"
+)
+
+// rankPost scores a post by how many times the query's phrases appear in it,
+// weighting its title above its description like the search_vector column.
+// It reports false if the post doesn't match. Unlike Postgres it matches
+// whole words without stemming.
+func rankPost(query search.Query, title, description string) (float32, bool) {
+	if query.Empty() {
+		return 0, false
+	}
+
+	titleWords, descriptionWords := searchWords(title), searchWords(description)
+
+	for _, phrase := range query.Exclude {
+		if countPhrase(titleWords, phrase)+countPhrase(descriptionWords, phrase) > 0 {
+			return 0, false
+		}
+	}
+
+	var rank float32
+	for _, group := range query.Groups {
+		matched := false
+		for _, phrase := range group {
+			titleMatches, descriptionMatches := countPhrase(titleWords, phrase), countPhrase(descriptionWords, phrase)
+			if titleMatches+descriptionMatches > 0 {
+				matched = true
+				rank += float32(titleMatches) + 0.4*float32(descriptionMatches)
+			}
+		}
+		if !matched {
+			return 0, false
+		}
+	}
+	return rank, true
+}
+
+// searchWords splits s into lowercase words, which are what queries match
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func countPhrase(words []string, phrase search.Phrase) int {
+	count := 0
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		matched := true
+		for j, word := range phrase {
+			if words[i+j] != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,89 @@
+package memdb
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+func (q *Queries) GetStarredPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetStarredPostsForUserRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var items []database.GetStarredPostsForUserRow
+	for _, star := range q.starredPosts {
+		if star.UserID != userID {
+			continue
+		}
+		post := q.posts[q.postIndex(star.PostID)]
+		feed := q.feeds[q.feedIndex(post.FeedID)]
+		items = append(items, database.GetStarredPostsForUserRow{
+			ID:                  post.ID,
+			CreatedAt:           post.CreatedAt,
+			UpdatedAt:           post.UpdatedAt,
+			Title:               post.Title,
+			Url:                 post.Url,
+			Description:         post.Description,
+			PublishedAt:         post.PublishedAt,
+			FeedID:              post.FeedID,
+			PublishedAtInferred: post.PublishedAtInferred,
+			Guid:                post.Guid,
+			Content:             post.Content,
+			Author:              post.Author,
+			FeedName:            feed.Name,
+			StarredAt:           star.CreatedAt,
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].StarredAt.After(items[j].StarredAt)
+	})
+	return items, nil
+}
+
+func (q *Queries) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.userIndex(arg.UserID) < 0 {
+		return foreignKeyViolation("starred_posts", "starred_posts_user_id_fkey")
+	}
+	if q.postIndex(arg.PostID) < 0 {
+		return foreignKeyViolation("starred_posts", "starred_posts_post_id_fkey")
+	}
+	for _, star := range q.starredPosts {
+		if star.UserID == arg.UserID && star.PostID == arg.PostID {
+			return nil
+		}
+	}
+
+	q.starredPosts = append(q.starredPosts, database.StarredPost{
+		UserID:    arg.UserID,
+		PostID:    arg.PostID,
+		CreatedAt: timestamp(arg.CreatedAt),
+	})
+	return nil
+}
+
+func (q *Queries) UnstarPost(ctx context.Context, arg database.UnstarPostParams) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	before := len(q.starredPosts)
+	q.starredPosts = filter(q.starredPosts, func(star database.StarredPost) bool {
+		return star.UserID != arg.UserID || star.PostID != arg.PostID
+	})
+	return int64(before - len(q.starredPosts)), nil
+}
+
+// isStarred reports whether any user starred the post
+func (q *Queries) isStarred(postID int32) bool {
+	for _, star := range q.starredPosts {
+		if star.PostID == postID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package memdb
+
+import (
+	"context"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+func (q *Queries) MarkFeedRead(ctx context.Context, arg database.MarkFeedReadParams) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var marked int64
+	for _, post := range q.posts {
+		feed := q.feeds[q.feedIndex(post.FeedID)]
+		if feed.Url != arg.Url || q.isRead(arg.UserID, post.ID) {
+			continue
+		}
+		if q.userIndex(arg.UserID) < 0 {
+			return 0, foreignKeyViolation("user_post_state", "user_post_state_user_id_fkey")
+		}
+		q.userPostStates = append(q.userPostStates, database.UserPostState{
+			UserID: arg.UserID,
+			PostID: post.ID,
+			ReadAt: timestamp(arg.ReadAt),
+		})
+		marked++
+	}
+	return marked, nil
+}
+
+func (q *Queries) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.userIndex(arg.UserID) < 0 {
+		return foreignKeyViolation("user_post_state", "user_post_state_user_id_fkey")
+	}
+	if q.postIndex(arg.PostID) < 0 {
+		return foreignKeyViolation("user_post_state", "user_post_state_post_id_fkey")
+	}
+	if q.isRead(arg.UserID, arg.PostID) {
+		return nil
+	}
+
+	q.userPostStates = append(q.userPostStates, database.UserPostState{
+		UserID: arg.UserID,
+		PostID: arg.PostID,
+		ReadAt: timestamp(arg.ReadAt),
+	})
+	return nil
+}
+
+func (q *Queries) isRead(userID uuid.UUID, postID int32) bool {
+	for _, state := range q.userPostStates {
+		if state.UserID == userID && state.PostID == postID {
+			return true
+		}
+	}
+	return false
+}
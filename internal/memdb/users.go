@@ -0,0 +1,96 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+func (q *Queries) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, user := range q.users {
+		if user.ID == arg.ID {
+			return database.User{}, uniqueViolation("users_pkey")
+		}
+		if user.Name == arg.Name {
+			return database.User{}, uniqueViolation("users_name_key")
+		}
+	}
+
+	user := database.User{
+		ID:        arg.ID,
+		CreatedAt: timestamp(arg.CreatedAt),
+		UpdatedAt: timestamp(arg.UpdatedAt),
+		Name:      arg.Name,
+		ApiKey:    newAPIKey(),
+	}
+	q.users = append(q.users, user)
+	return user, nil
+}
+
+func (q *Queries) DeleteUsers(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.deleteUsers(func(database.User) bool { return true })
+	return nil
+}
+
+func (q *Queries) GetUserByAPIKey(ctx context.Context, apiKey string) (database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, user := range q.users {
+		if user.ApiKey == apiKey {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.userIndex(id)
+	if i < 0 {
+		return database.User{}, sql.ErrNoRows
+	}
+	return q.users[i], nil
+}
+
+func (q *Queries) GetUserByName(ctx context.Context, name string) (database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, user := range q.users {
+		if user.Name == name {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (q *Queries) GetUsers(ctx context.Context) ([]database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return append([]database.User(nil), q.users...), nil
+}
+
+func (q *Queries) RotateUserAPIKey(ctx context.Context, arg database.RotateUserAPIKeyParams) (database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := q.userIndex(arg.ID)
+	if i < 0 {
+		return database.User{}, sql.ErrNoRows
+	}
+	q.users[i].ApiKey = newAPIKey()
+	q.users[i].UpdatedAt = timestamp(arg.UpdatedAt)
+	return q.users[i], nil
+}
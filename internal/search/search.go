@@ -0,0 +1,77 @@
+// Package search parses the web search syntax gator search accepts, which is
+// the syntax Postgres' websearch_to_tsquery understands, so that backends
+// without it can evaluate or translate the same queries.
+package search
+
+import "strings"
+
+// Phrase is a run of consecutive words
+type Phrase []string
+
+// Query is a parsed search: every group must match, where a group matches if
+// any of its phrases do, and no excluded phrase may match
+type Query struct {
+	Groups  [][]Phrase
+	Exclude []Phrase
+}
+
+// Empty reports whether nothing can match the query
+func (q Query) Empty() bool {
+	return len(q.Groups) == 0
+}
+
+// Parse reads "quoted phrases", -excluded words and or between alternatives.
+// Every other word must match. words splits a term into the words a backend
+// indexes; terms without any are ignored.
+func Parse(query string, words func(string) []string) Query {
+	var parsed Query
+	pendingOr := false
+
+	for query = strings.TrimSpace(query); query != ""; query = strings.TrimSpace(query) {
+		negate := false
+		if query[0] == '-' {
+			negate = true
+			query = query[1:]
+		}
+
+		var term string
+		if strings.HasPrefix(query, `"`) {
+			end := strings.Index(query[1:], `"`)
+			if end < 0 {
+				term, query = query[1:], ""
+			} else {
+				term, query = query[1:end+1], query[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(query, " \t\n\"")
+			if end < 0 {
+				term, query = query, ""
+			} else {
+				term, query = query[:end], query[end:]
+			}
+		}
+
+		if !negate && strings.EqualFold(term, "or") {
+			pendingOr = len(parsed.Groups) > 0
+			continue
+		}
+
+		phrase := Phrase(words(term))
+		if len(phrase) == 0 {
+			continue
+		}
+
+		switch {
+		case negate:
+			parsed.Exclude = append(parsed.Exclude, phrase)
+		case pendingOr:
+			last := len(parsed.Groups) - 1
+			parsed.Groups[last] = append(parsed.Groups[last], phrase)
+			pendingOr = false
+		default:
+			parsed.Groups = append(parsed.Groups, []Phrase{phrase})
+		}
+	}
+
+	return parsed
+}
@@ -0,0 +1,39 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Query
+	}{
+		{"go", Query{Groups: [][]Phrase{{{"go"}}}}},
+		{"go generics", Query{Groups: [][]Phrase{{{"go"}}, {{"generics"}}}}},
+		{`"go generics" release`, Query{Groups: [][]Phrase{{{"go", "generics"}}, {{"release"}}}}},
+		{`go -rust -"rust lang"`, Query{Groups: [][]Phrase{{{"go"}}}, Exclude: []Phrase{{"rust"}, {"rust", "lang"}}}},
+		{"go or rust zig", Query{Groups: [][]Phrase{{{"go"}, {"rust"}}, {{"zig"}}}}},
+		{"go OR -rust", Query{Groups: [][]Phrase{{{"go"}}}, Exclude: []Phrase{{"rust"}}}},
+		{"or go", Query{Groups: [][]Phrase{{{"go"}}}}},
+		{"go or +++ rust", Query{Groups: [][]Phrase{{{"go"}, {"rust"}}}}},
+		{`"unterminated phrase`, Query{Groups: [][]Phrase{{{"unterminated", "phrase"}}}}},
+		{"-rust", Query{Exclude: []Phrase{{"rust"}}}},
+		{"  ", Query{}},
+	}
+
+	words := func(s string) []string {
+		return strings.FieldsFunc(s, func(r rune) bool { return r == '+' || r == '-' || r == ' ' })
+	}
+	for _, tt := range tests {
+		got := Parse(tt.query, words)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+		if got.Empty() != (len(tt.want.Groups) == 0) {
+			t.Errorf("Parse(%q).Empty() = %v", tt.query, got.Empty())
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package sqlitedb
+
+import (
+	"context"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+)
+
+const addPostCategory = `
+INSERT INTO post_categories (post_id, category_id)
+VALUES (?1, ?2)
+ON CONFLICT (post_id, category_id) DO NOTHING
+`
+
+func (q *Queries) AddPostCategory(ctx context.Context, arg database.AddPostCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, addPostCategory, arg.PostID, arg.CategoryID)
+	return err
+}
+
+const createCategory = `
+INSERT INTO categories (name)
+VALUES (?1)
+ON CONFLICT (name) DO UPDATE SET name = excluded.name
+RETURNING id, name
+`
+
+func (q *Queries) CreateCategory(ctx context.Context, name string) (database.Category, error) {
+	row := q.db.QueryRowContext(ctx, createCategory, name)
+	var i database.Category
+	err := row.Scan(&i.ID, &i.Name)
+	return i, err
+}
+
+const getCategoriesForPost = `
+SELECT categories.id, categories.name FROM categories
+INNER JOIN post_categories ON categories.id = post_categories.category_id
+WHERE post_categories.post_id = ?1
+ORDER BY categories.name
+`
+
+func (q *Queries) GetCategoriesForPost(ctx context.Context, postID int32) ([]database.Category, error) {
+	rows, err := q.db.QueryContext(ctx, getCategoriesForPost, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Category
+	for rows.Next() {
+		var i database.Category
+		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,127 @@
+// Package sqlitedb implements database.Querier on SQLite, so gator can run
+// without a Postgres server. Its queries mirror sql/queries, rewritten for
+// SQLite, against the schema in sql/sqlite/schema.
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+)
+
+func New(db database.DBTX) *Queries {
+	return &Queries{db: db}
+}
+
+type Queries struct {
+	db database.DBTX
+}
+
+var _ database.Querier = (*Queries)(nil)
+
+func (q *Queries) WithTx(tx *sql.Tx) *Queries {
+	return &Queries{
+		db: tx,
+	}
+}
+
+// NewStore returns a database.Store running the SQLite queries on db
+func NewStore(db *sql.DB) database.Store {
+	return &store{Queries: New(db), db: db}
+}
+
+type store struct {
+	*Queries
+	db *sql.DB
+}
+
+func (s *store) InTx(ctx context.Context, fn func(database.Querier) error) error {
+	return database.RunInTx(ctx, s.db, func(tx *sql.Tx) error {
+		return fn(s.WithTx(tx))
+	})
+}
+
+// bind converts time arguments to UTC. SQLite stores times as text, which
+// only compares in time order when every value has the same offset.
+func bind(args ...interface{}) []interface{} {
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			args[i] = v.UTC()
+		case sql.NullTime:
+			if v.Valid {
+				args[i] = v.Time.UTC()
+			} else {
+				args[i] = nil
+			}
+		}
+	}
+	return args
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const userColumns = `users.id, users.created_at, users.updated_at, users.name, users.api_key`
+
+func scanUser(row scanner) (database.User, error) {
+	var i database.User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.ApiKey,
+	)
+	return i, err
+}
+
+const feedColumns = `feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.etag, feeds.last_modified, feeds.claimed_until, feeds.consecutive_failures, feeds.last_error, feeds.last_succeeded_at, feeds.backoff_until, feeds.next_fetch_at`
+
+func scanFeed(row scanner) (database.Feed, error) {
+	var i database.Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.BackoffUntil,
+		&i.NextFetchAt,
+	)
+	return i, err
+}
+
+// postColumns has no search_vector: SQLite indexes posts in the posts_fts
+// table instead, and Post.SearchVector is always nil
+const postColumns = `posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.published_at_inferred, posts.guid, posts.content, posts.author`
+
+func scanPost(row scanner) (database.Post, error) {
+	var i database.Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.PublishedAtInferred,
+		&i.Guid,
+		&i.Content,
+		&i.Author,
+	)
+	return i, err
+}
@@ -0,0 +1,403 @@
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	sqliteschema "github.com/Breadumi/aggreGator/sql/sqlite/schema"
+	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
+	_ "modernc.org/sqlite"
+)
+
+// newTestDB migrates a new database file with the pragmas gator opens
+// SQLite with
+func newTestDB(t *testing.T) *Queries {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gator.db")
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := goose.NewProvider(goose.DialectSQLite3, db, sqliteschema.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return New(db)
+}
+
+var testNow = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func testUser(t *testing.T, q *Queries, name string) database.User {
+	t.Helper()
+	user, err := q.CreateUser(context.Background(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: testNow,
+		UpdatedAt: testNow,
+		Name:      name,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func testFeed(t *testing.T, q *Queries, user database.User, url string) database.Feed {
+	t.Helper()
+	feed, err := q.CreateFeed(context.Background(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: testNow,
+		UpdatedAt: testNow,
+		Name:      url,
+		Url:       url,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+func testPost(t *testing.T, q *Queries, feed database.Feed, guid, title, description string, publishedAt time.Time) database.Post {
+	t.Helper()
+	post, err := q.CreatePost(context.Background(), database.CreatePostParams{
+		CreatedAt:   testNow,
+		UpdatedAt:   testNow,
+		Title:       title,
+		Url:         "https://example.com/" + guid,
+		Description: description,
+		PublishedAt: publishedAt,
+		FeedID:      feed.ID,
+		Guid:        guid,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+func TestClaimNextFeedLease(t *testing.T) {
+	ctx := context.Background()
+	q := newTestDB(t)
+	alice := testUser(t, q, "alice")
+	feed := testFeed(t, q, alice, "https://example.com/feed")
+
+	claim := func(now time.Time) (database.Feed, error) {
+		return q.ClaimNextFeed(ctx, database.ClaimNextFeedParams{
+			Now:          now,
+			ClaimedUntil: sql.NullTime{Time: now.Add(time.Minute), Valid: true},
+		})
+	}
+
+	claimed, err := claim(testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed.ID != feed.ID || !claimed.ClaimedUntil.Time.Equal(testNow.Add(time.Minute)) {
+		t.Fatalf("claimed %v until %v, want %v until %v", claimed.ID, claimed.ClaimedUntil, feed.ID, testNow.Add(time.Minute))
+	}
+	if !claimed.LastFetchedAt.Valid || !claimed.LastFetchedAt.Time.Equal(testNow) {
+		t.Errorf("last_fetched_at = %v, want %v", claimed.LastFetchedAt, testNow)
+	}
+
+	// like Postgres, the lease holds until claimed_until has passed
+	for _, now := range []time.Time{testNow.Add(time.Second), testNow.Add(time.Minute)} {
+		if _, err := claim(now); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("claiming at %v during the lease: err = %v, want sql.ErrNoRows", now, err)
+		}
+	}
+
+	// the worker holding the lease releases it; another claim can't
+	stale := sql.NullTime{Time: testNow.Add(time.Second), Valid: true}
+	if err := q.ReleaseFeedClaim(ctx, database.ReleaseFeedClaimParams{ID: feed.ID, ClaimedUntil: stale}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := claim(testNow.Add(2 * time.Second)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("claiming after a release under another claim: err = %v, want sql.ErrNoRows", err)
+	}
+
+	// an expired lease is taken over, and the old claim no longer updates the feed
+	expired := testNow.Add(2 * time.Minute)
+	if _, err := claim(expired); err != nil {
+		t.Fatalf("claiming after the lease expired: %v", err)
+	}
+	err = q.SetFeedNextFetch(ctx, database.SetFeedNextFetchParams{
+		ID:           feed.ID,
+		NextFetchAt:  sql.NullTime{Time: expired.Add(time.Hour), Valid: true},
+		ClaimedUntil: claimed.ClaimedUntil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := q.GetFeedByID(ctx, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.NextFetchAt.Valid {
+		t.Errorf("expired claim set next_fetch_at to %v", got.NextFetchAt.Time)
+	}
+	if !got.ClaimedUntil.Time.Equal(expired.Add(time.Minute)) {
+		t.Errorf("claimed_until = %v, want %v", got.ClaimedUntil.Time, expired.Add(time.Minute))
+	}
+}
+
+func TestClaimNextFeedSchedule(t *testing.T) {
+	ctx := context.Background()
+	q := newTestDB(t)
+	alice := testUser(t, q, "alice")
+	bob := testUser(t, q, "bob")
+	due := testFeed(t, q, alice, "https://example.com/due")
+	later := testFeed(t, q, alice, "https://example.com/later")
+	backedOff := testFeed(t, q, alice, "https://example.com/backed-off")
+	never := testFeed(t, q, alice, "https://example.com/never")
+
+	// each feed is claimed and released again, so that its schedule can be set
+	// under a claim the way agg does
+	setSchedule := func(feed database.Feed, nextFetch, backoff time.Time) {
+		t.Helper()
+		claimedUntil := sql.NullTime{Time: testNow.Add(-time.Hour), Valid: true}
+		claimed, err := q.ClaimNextFeed(ctx, database.ClaimNextFeedParams{
+			Now:          testNow.Add(-2 * time.Hour),
+			ClaimedUntil: claimedUntil,
+			Url:          sql.NullString{String: feed.Url, Valid: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = q.SetFeedNextFetch(ctx, database.SetFeedNextFetchParams{
+			ID:           claimed.ID,
+			NextFetchAt:  sql.NullTime{Time: nextFetch, Valid: true},
+			ClaimedUntil: claimedUntil,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = q.MarkFeedFailed(ctx, database.MarkFeedFailedParams{
+			ID:           claimed.ID,
+			LastError:    sql.NullString{String: "test", Valid: true},
+			BackoffUntil: sql.NullTime{Time: backoff, Valid: true},
+			ClaimedUntil: claimedUntil,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.ReleaseFeedClaim(ctx, database.ReleaseFeedClaimParams{ID: claimed.ID, ClaimedUntil: claimedUntil}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setSchedule(due, testNow.Add(-time.Minute), testNow.Add(-time.Minute))
+	setSchedule(later, testNow.Add(time.Minute), testNow.Add(-time.Minute))
+	setSchedule(backedOff, testNow.Add(-time.Minute), testNow.Add(time.Minute))
+
+	if _, err := q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		CreatedAt: testNow,
+		UpdatedAt: testNow,
+		UserID:    bob.ID,
+		FeedID:    later.ID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	claim := func(arg database.ClaimNextFeedParams) string {
+		t.Helper()
+		arg.Now = testNow
+		arg.ClaimedUntil = sql.NullTime{Time: testNow.Add(time.Minute), Valid: true}
+		feed, err := q.ClaimNextFeed(ctx, arg)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ""
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return feed.Url
+	}
+
+	// bob follows only a feed that isn't due yet
+	if got := claim(database.ClaimNextFeedParams{UserID: uuid.NullUUID{UUID: bob.ID, Valid: true}}); got != "" {
+		t.Errorf("claimed %s for bob, want nothing", got)
+	}
+	// feeds never fetched come first, then the one due longest
+	if got := claim(database.ClaimNextFeedParams{}); got != never.Url {
+		t.Errorf("first claim = %s, want %s", got, never.Url)
+	}
+	if got := claim(database.ClaimNextFeedParams{}); got != due.Url {
+		t.Errorf("second claim = %s, want %s", got, due.Url)
+	}
+	if got := claim(database.ClaimNextFeedParams{}); got != "" {
+		t.Errorf("third claim = %s, want nothing before next_fetch_at and backoff_until", got)
+	}
+
+	// a feed asked for by url is claimed even if it isn't due, but not while
+	// another worker holds it
+	for _, feed := range []database.Feed{later, backedOff} {
+		if got := claim(database.ClaimNextFeedParams{Url: sql.NullString{String: feed.Url, Valid: true}}); got != feed.Url {
+			t.Errorf("claim by url = %q, want %s", got, feed.Url)
+		}
+	}
+	if got := claim(database.ClaimNextFeedParams{Url: sql.NullString{String: due.Url, Valid: true}}); got != "" {
+		t.Errorf("claimed %s by url while it was claimed", got)
+	}
+}
+
+func TestPrunedPostsAreNotCreatedAgain(t *testing.T) {
+	ctx := context.Background()
+	q := newTestDB(t)
+	alice := testUser(t, q, "alice")
+	feed := testFeed(t, q, alice, "https://example.com/feed")
+
+	cutoff := testNow.Add(-24 * time.Hour)
+	old := testPost(t, q, feed, "old", "Old", "", cutoff.Add(-time.Hour))
+	starred := testPost(t, q, feed, "starred", "Starred", "", cutoff.Add(-time.Hour))
+	recent := testPost(t, q, feed, "recent", "Recent", "", cutoff.Add(time.Hour))
+	if err := q.StarPost(ctx, database.StarPostParams{UserID: alice.ID, PostID: starred.ID, CreatedAt: testNow}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a time with an offset is compared as the UTC instant it stands for
+	deleted, err := q.DeleteUnstarredPostsBefore(ctx, cutoff.In(time.FixedZone("EDT", -4*60*60)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted %d posts, want 1", deleted)
+	}
+
+	for _, tt := range []struct {
+		post   database.Post
+		pruned bool
+	}{
+		{old, true},
+		{starred, false},
+		{recent, false},
+	} {
+		_, err := q.GetPostByID(ctx, tt.post.ID)
+		if exists := err == nil; exists == tt.pruned {
+			t.Errorf("post %s exists = %v, want %v (err = %v)", tt.post.Guid, exists, !tt.pruned, err)
+		}
+	}
+
+	// like ON CONFLICT, a pruned guid makes CreatePost return no row
+	_, err = q.CreatePost(ctx, database.CreatePostParams{
+		CreatedAt:   testNow,
+		UpdatedAt:   testNow,
+		Title:       "Old",
+		PublishedAt: cutoff.Add(-time.Hour),
+		FeedID:      feed.ID,
+		Guid:        "old",
+	})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("creating a pruned post: err = %v, want sql.ErrNoRows", err)
+	}
+
+	// pruning again finds nothing new and doesn't trip over the recorded guid
+	if deleted, err := q.DeleteUnstarredPostsBefore(ctx, cutoff); err != nil || deleted != 0 {
+		t.Errorf("pruning again deleted %d posts, err = %v; want 0", deleted, err)
+	}
+
+	// the pruned guids go with their feed when its user is deleted
+	if err := q.DeleteUsers(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var pruned int
+	if err := q.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pruned_posts").Scan(&pruned); err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 0 {
+		t.Errorf("%d pruned guids outlived their feed", pruned)
+	}
+}
+
+func TestCreateFeedFollowReturnsNames(t *testing.T) {
+	ctx := context.Background()
+	q := newTestDB(t)
+	alice := testUser(t, q, "alice")
+	bob := testUser(t, q, "bob")
+	feed := testFeed(t, q, alice, "https://example.com/feed")
+
+	arg := database.CreateFeedFollowParams{
+		CreatedAt: testNow,
+		UpdatedAt: testNow,
+		UserID:    bob.ID,
+		FeedID:    feed.ID,
+		Folder:    "News",
+	}
+	follow, err := q.CreateFeedFollow(ctx, arg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if follow.ID == 0 || follow.UserID != bob.ID || follow.FeedID != feed.ID || follow.Folder != "News" || !follow.CreatedAt.Equal(testNow) {
+		t.Errorf("follow = %+v", follow)
+	}
+	if follow.FeedName != feed.Name || follow.UserName != "bob" {
+		t.Errorf("follow names = %q, %q; want %q, %q", follow.FeedName, follow.UserName, feed.Name, "bob")
+	}
+
+	// a second follow violates the unique constraint, as in Postgres, and
+	// doesn't return the first
+	if _, err := q.CreateFeedFollow(ctx, arg); err == nil {
+		t.Error("following a feed twice succeeded")
+	}
+
+	// so does following a feed that doesn't exist
+	arg.FeedID = uuid.New()
+	if _, err := q.CreateFeedFollow(ctx, arg); err == nil {
+		t.Error("following a missing feed succeeded")
+	}
+
+	follows, err := q.GetFeedFollowsForUser(ctx, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 1 {
+		t.Errorf("bob has %d follows, want 1", len(follows))
+	}
+}
+
+func TestStoreInTxRollsBack(t *testing.T) {
+	ctx := context.Background()
+	q := newTestDB(t)
+	store := NewStore(q.db.(*sql.DB))
+	alice := testUser(t, q, "alice")
+	feed := testFeed(t, q, alice, "https://example.com/feed")
+
+	failed := errors.New("failed")
+	err := store.InTx(ctx, func(db database.Querier) error {
+		post, err := db.CreatePost(ctx, database.CreatePostParams{
+			CreatedAt:   testNow,
+			UpdatedAt:   testNow,
+			Title:       "Episode",
+			PublishedAt: testNow,
+			FeedID:      feed.ID,
+			Guid:        "episode",
+		})
+		if err != nil {
+			return err
+		}
+		if err := db.CreateEnclosure(ctx, database.CreateEnclosureParams{PostID: post.ID, Url: "https://example.com/episode.mp3"}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("InTx returned %v, want %v", err, failed)
+	}
+
+	var posts, enclosures int
+	row := q.db.QueryRowContext(ctx, "SELECT (SELECT COUNT(*) FROM posts), (SELECT COUNT(*) FROM enclosures)")
+	if err := row.Scan(&posts, &enclosures); err != nil {
+		t.Fatal(err)
+	}
+	if posts != 0 || enclosures != 0 {
+		t.Errorf("rolled back transaction left %d posts and %d enclosures", posts, enclosures)
+	}
+}
@@ -0,0 +1,140 @@
+package sqlitedb
+
+import (
+	"context"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+)
+
+const createEnclosure = `
+INSERT INTO enclosures (post_id, url, mime_type, size_bytes, duration_seconds)
+VALUES (?1, ?2, ?3, ?4, ?5)
+ON CONFLICT (post_id, url) DO NOTHING
+`
+
+func (q *Queries) CreateEnclosure(ctx context.Context, arg database.CreateEnclosureParams) error {
+	_, err := q.db.ExecContext(ctx, createEnclosure,
+		arg.PostID,
+		arg.Url,
+		arg.MimeType,
+		arg.SizeBytes,
+		arg.DurationSeconds,
+	)
+	return err
+}
+
+const getEnclosureByID = `
+SELECT id, post_id, url, mime_type, size_bytes, duration_seconds FROM enclosures
+WHERE id = ?1
+`
+
+func (q *Queries) GetEnclosureByID(ctx context.Context, id int32) (database.Enclosure, error) {
+	row := q.db.QueryRowContext(ctx, getEnclosureByID, id)
+	var i database.Enclosure
+	err := row.Scan(
+		&i.ID,
+		&i.PostID,
+		&i.Url,
+		&i.MimeType,
+		&i.SizeBytes,
+		&i.DurationSeconds,
+	)
+	return i, err
+}
+
+const getEnclosureDownload = `
+SELECT user_id, enclosure_id, path, size_bytes, downloaded_at FROM enclosure_downloads
+WHERE user_id = ?1
+AND enclosure_id = ?2
+`
+
+func (q *Queries) GetEnclosureDownload(ctx context.Context, arg database.GetEnclosureDownloadParams) (database.EnclosureDownload, error) {
+	row := q.db.QueryRowContext(ctx, getEnclosureDownload, arg.UserID, arg.EnclosureID)
+	var i database.EnclosureDownload
+	err := row.Scan(
+		&i.UserID,
+		&i.EnclosureID,
+		&i.Path,
+		&i.SizeBytes,
+		&i.DownloadedAt,
+	)
+	return i, err
+}
+
+const getEnclosuresByUser = `
+SELECT
+    enclosures.id, enclosures.post_id, enclosures.url, enclosures.mime_type, enclosures.size_bytes, enclosures.duration_seconds,
+    posts.title AS post_title, posts.published_at, feeds.name AS feed_name,
+    enclosure_downloads.path AS download_path, enclosure_downloads.downloaded_at
+FROM enclosures
+INNER JOIN posts ON enclosures.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN enclosure_downloads ON enclosure_downloads.enclosure_id = enclosures.id
+    AND enclosure_downloads.user_id = ?1
+WHERE posts.feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = ?1
+)
+AND (?2 IS NULL OR feeds.url = ?2)
+AND (NOT ?3 OR enclosure_downloads.enclosure_id IS NULL)
+ORDER BY posts.published_at DESC, enclosures.id
+LIMIT ?4
+`
+
+func (q *Queries) GetEnclosuresByUser(ctx context.Context, arg database.GetEnclosuresByUserParams) ([]database.GetEnclosuresByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEnclosuresByUser,
+		arg.UserID,
+		arg.FeedUrl,
+		arg.PendingOnly,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetEnclosuresByUserRow
+	for rows.Next() {
+		var i database.GetEnclosuresByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PostID,
+			&i.Url,
+			&i.MimeType,
+			&i.SizeBytes,
+			&i.DurationSeconds,
+			&i.PostTitle,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.DownloadPath,
+			&i.DownloadedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEnclosureDownloaded = `
+INSERT INTO enclosure_downloads (user_id, enclosure_id, path, size_bytes, downloaded_at)
+VALUES (?1, ?2, ?3, ?4, ?5)
+ON CONFLICT (user_id, enclosure_id) DO UPDATE
+SET path = excluded.path, size_bytes = excluded.size_bytes, downloaded_at = excluded.downloaded_at
+`
+
+func (q *Queries) MarkEnclosureDownloaded(ctx context.Context, arg database.MarkEnclosureDownloadedParams) error {
+	_, err := q.db.ExecContext(ctx, markEnclosureDownloaded, bind(
+		arg.UserID,
+		arg.EnclosureID,
+		arg.Path,
+		arg.SizeBytes,
+		arg.DownloadedAt,
+	)...)
+	return err
+}
@@ -0,0 +1,154 @@
+package sqlitedb
+
+import (
+	"context"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+const createFeedFollow = `
+INSERT INTO feed_follows (created_at, updated_at, user_id, feed_id, folder)
+VALUES (?1, ?2, ?3, ?4, ?5)
+RETURNING id
+`
+
+// getFeedFollowRow stands in for the CTE the Postgres query uses, since
+// SQLite doesn't allow an INSERT inside WITH
+const getFeedFollowRow = `
+SELECT
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder,
+    feeds.name AS feed_name,
+    users.name AS user_name
+FROM feed_follows
+INNER JOIN users ON feed_follows.user_id = users.id
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.id = ?1
+`
+
+func (q *Queries) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	var i database.CreateFeedFollowRow
+	err := q.db.QueryRowContext(ctx, createFeedFollow, bind(
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.Folder,
+	)...).Scan(&i.ID)
+	if err != nil {
+		return i, err
+	}
+
+	row := q.db.QueryRowContext(ctx, getFeedFollowRow, i.ID)
+	err = row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.Folder,
+		&i.FeedName,
+		&i.UserName,
+	)
+	return i, err
+}
+
+const deleteFeedFollow = `
+DELETE FROM feed_follows
+WHERE feed_follows.user_id = ?1
+AND feed_follows.feed_id = (
+    SELECT feeds.id FROM feeds WHERE feeds.url = ?2
+)
+`
+
+func (q *Queries) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedFollow, arg.UserID, arg.Url)
+	return err
+}
+
+const getFeedFollows = `
+SELECT id, created_at, updated_at, user_id, feed_id, folder FROM feed_follows
+`
+
+func (q *Queries) GetFeedFollows(ctx context.Context) ([]database.FeedFollow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.FeedFollow
+	for rows.Next() {
+		var i database.FeedFollow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.Folder,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedFollowsForUser = `
+SELECT
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder,
+    users.name AS user_name, feeds.name AS feed_name, feeds.url AS feed_url,
+    (
+        SELECT COUNT(*) FROM posts
+        WHERE posts.feed_id = feed_follows.feed_id
+        AND NOT EXISTS (
+            SELECT 1 FROM user_post_state
+            WHERE user_post_state.user_id = feed_follows.user_id
+            AND user_post_state.post_id = posts.id
+        )
+    ) AS unread_count
+FROM feed_follows
+INNER JOIN users ON feed_follows.user_id = users.id
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = ?1
+`
+
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetFeedFollowsForUserRow
+	for rows.Next() {
+		var i database.GetFeedFollowsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.Folder,
+			&i.UserName,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
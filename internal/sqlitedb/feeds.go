@@ -0,0 +1,161 @@
+package sqlitedb
+
+import (
+	"context"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+// claimNextFeed needs no row locks: SQLite runs one write at a time, so the
+// feed selected by the subquery can't be claimed by another worker first
+const claimNextFeed = `
+UPDATE feeds
+SET updated_at = ?1, last_fetched_at = ?1, claimed_until = ?2
+WHERE feeds.id = (
+    SELECT id FROM feeds
+    WHERE (feeds.claimed_until IS NULL OR feeds.claimed_until < ?1)
+    AND (?3 IS NOT NULL OR (
+        (feeds.backoff_until IS NULL OR feeds.backoff_until <= ?1)
+        AND (feeds.next_fetch_at IS NULL OR feeds.next_fetch_at <= ?1)
+    ))
+    AND (?3 IS NULL OR feeds.url = ?3)
+    AND (?4 IS NULL OR feeds.id IN (
+        SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = ?4
+    ))
+    ORDER BY feeds.next_fetch_at ASC NULLS FIRST, feeds.last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+)
+RETURNING ` + feedColumns
+
+func (q *Queries) ClaimNextFeed(ctx context.Context, arg database.ClaimNextFeedParams) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, claimNextFeed, bind(
+		arg.Now,
+		arg.ClaimedUntil,
+		arg.Url,
+		arg.UserID,
+	)...)
+	return scanFeed(row)
+}
+
+const createFeed = `
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+RETURNING ` + feedColumns
+
+func (q *Queries) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, createFeed, bind(
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Url,
+		arg.UserID,
+	)...)
+	return scanFeed(row)
+}
+
+const getFeedByID = `
+SELECT ` + feedColumns + ` FROM feeds
+WHERE id = ?1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	return scanFeed(q.db.QueryRowContext(ctx, getFeedByID, id))
+}
+
+const getFeedByURL = `
+SELECT ` + feedColumns + ` FROM feeds
+WHERE url = ?1
+`
+
+func (q *Queries) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	return scanFeed(q.db.QueryRowContext(ctx, getFeedByURL, url))
+}
+
+const getFeeds = `
+SELECT ` + feedColumns + ` FROM feeds
+`
+
+func (q *Queries) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Feed
+	for rows.Next() {
+		i, err := scanFeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedFailed = `
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1, last_error = ?2, backoff_until = ?3
+WHERE feeds.id = ?1 AND feeds.claimed_until = ?4
+`
+
+func (q *Queries) MarkFeedFailed(ctx context.Context, arg database.MarkFeedFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFailed, bind(
+		arg.ID,
+		arg.LastError,
+		arg.BackoffUntil,
+		arg.ClaimedUntil,
+	)...)
+	return err
+}
+
+const markFeedSucceeded = `
+UPDATE feeds
+SET consecutive_failures = 0, last_error = NULL, last_succeeded_at = ?2, backoff_until = NULL
+WHERE feeds.id = ?1 AND feeds.claimed_until = ?3
+`
+
+func (q *Queries) MarkFeedSucceeded(ctx context.Context, arg database.MarkFeedSucceededParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedSucceeded, bind(arg.ID, arg.LastSucceededAt, arg.ClaimedUntil)...)
+	return err
+}
+
+const releaseFeedClaim = `
+UPDATE feeds
+SET claimed_until = NULL
+WHERE feeds.id = ?1 AND feeds.claimed_until = ?2
+`
+
+func (q *Queries) ReleaseFeedClaim(ctx context.Context, arg database.ReleaseFeedClaimParams) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedClaim, bind(arg.ID, arg.ClaimedUntil)...)
+	return err
+}
+
+const setFeedNextFetch = `
+UPDATE feeds
+SET next_fetch_at = ?2
+WHERE feeds.id = ?1 AND feeds.claimed_until = ?3
+`
+
+func (q *Queries) SetFeedNextFetch(ctx context.Context, arg database.SetFeedNextFetchParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedNextFetch, bind(arg.ID, arg.NextFetchAt, arg.ClaimedUntil)...)
+	return err
+}
+
+const updateFeedCacheHeaders = `
+UPDATE feeds
+SET etag = ?2, last_modified = ?3
+WHERE feeds.id = ?1
+`
+
+func (q *Queries) UpdateFeedCacheHeaders(ctx context.Context, arg database.UpdateFeedCacheHeadersParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCacheHeaders, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
@@ -0,0 +1,259 @@
+package sqlitedb
+
+import (
+	"context"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+const createPost = `
+INSERT INTO posts (created_at, updated_at, title, url, description, published_at, feed_id, published_at_inferred, guid, content, author)
+SELECT ?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11
+WHERE NOT EXISTS (
+    SELECT 1 FROM pruned_posts
+    WHERE pruned_posts.feed_id = ?7 AND pruned_posts.guid = ?9
+)
+ON CONFLICT (feed_id, guid) DO NOTHING
+RETURNING ` + postColumns
+
+func (q *Queries) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	row := q.db.QueryRowContext(ctx, createPost, bind(
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.PublishedAtInferred,
+		arg.Guid,
+		arg.Content,
+		arg.Author,
+	)...)
+	return scanPost(row)
+}
+
+// DeleteLegacyGUIDFeed always reports no rows: SQLite databases stored guids
+// from the start, so no feed has posts with urls in place of their guids
+func (q *Queries) DeleteLegacyGUIDFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+const unstarredPostsBefore = `
+WHERE posts.published_at < ?1
+AND NOT EXISTS (
+    SELECT 1 FROM starred_posts
+    WHERE starred_posts.post_id = posts.id
+)
+`
+
+// SQLite can't delete inside a WITH clause, so the guids are recorded in
+// pruned_posts first and the posts deleted after
+const recordPrunedPosts = `
+INSERT INTO pruned_posts (feed_id, guid)
+SELECT posts.feed_id, posts.guid FROM posts` + unstarredPostsBefore + `
+ON CONFLICT (feed_id, guid) DO NOTHING
+`
+
+const deleteUnstarredPostsBefore = `
+DELETE FROM posts` + unstarredPostsBefore
+
+func (q *Queries) DeleteUnstarredPostsBefore(ctx context.Context, publishedAt time.Time) (int64, error) {
+	_, err := q.db.ExecContext(ctx, recordPrunedPosts, bind(publishedAt)...)
+	if err != nil {
+		return 0, err
+	}
+	result, err := q.db.ExecContext(ctx, deleteUnstarredPostsBefore, bind(publishedAt)...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFeedPostingStats = `
+SELECT
+    COUNT(*) AS post_count,
+    CAST(COALESCE(ROUND((julianday(MAX(published_at)) - julianday(MIN(published_at))) * 86400), 0) AS INTEGER) AS span_seconds
+FROM (
+    SELECT published_at FROM posts
+    WHERE posts.feed_id = ?1
+    AND NOT posts.published_at_inferred
+    ORDER BY published_at DESC
+    LIMIT 10
+) AS recent_posts
+`
+
+func (q *Queries) GetFeedPostingStats(ctx context.Context, feedID uuid.UUID) (database.GetFeedPostingStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedPostingStats, feedID)
+	var i database.GetFeedPostingStatsRow
+	err := row.Scan(&i.PostCount, &i.SpanSeconds)
+	return i, err
+}
+
+const getPostByID = `
+SELECT ` + postColumns + ` FROM posts
+WHERE id = ?1
+`
+
+func (q *Queries) GetPostByID(ctx context.Context, id int32) (database.Post, error) {
+	return scanPost(q.db.QueryRowContext(ctx, getPostByID, id))
+}
+
+const getPostsByUser = `
+SELECT ` + postColumns + `, feeds.name AS feed_name, feeds.url AS feed_url FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = ?1
+)
+AND (?2 IS NULL OR posts.published_at >= ?2)
+AND (?3 IS NULL OR posts.published_at < ?3)
+AND (?4 IS NULL OR feeds.url = ?4)
+AND (?5 IS NULL OR EXISTS (
+    SELECT 1 FROM post_categories
+    INNER JOIN categories ON post_categories.category_id = categories.id
+    WHERE post_categories.post_id = posts.id
+    AND lower(categories.name) = lower(?5)
+))
+AND (?6 IS NULL OR lower(posts.author) = lower(?6))
+AND (NOT ?7 OR NOT EXISTS (
+    SELECT 1 FROM user_post_state
+    WHERE user_post_state.user_id = ?1
+    AND user_post_state.post_id = posts.id
+))
+ORDER BY posts.published_at DESC, posts.id DESC
+LIMIT ?8 OFFSET ?9
+`
+
+func (q *Queries) GetPostsByUser(ctx context.Context, arg database.GetPostsByUserParams) ([]database.GetPostsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByUser, bind(
+		arg.UserID,
+		arg.Since,
+		arg.Until,
+		arg.FeedUrl,
+		arg.Category,
+		arg.Author,
+		arg.UnreadOnly,
+		arg.Limit,
+		arg.Offset,
+	)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetPostsByUserRow
+	for rows.Next() {
+		var i database.GetPostsByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.PublishedAtInferred,
+			&i.Guid,
+			&i.Content,
+			&i.Author,
+			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// searchPosts weights title matches above description matches, as the
+// Postgres search_vector does. bm25 scores better matches lower, so the rank
+// is negated to sort like ts_rank.
+const searchPosts = `
+SELECT
+    posts.id, posts.title, posts.url, posts.description, posts.published_at, posts.published_at_inferred,
+    feeds.name AS feed_name,
+    -bm25(posts_fts, 1.0, 0.4) AS rank
+FROM posts_fts
+INNER JOIN posts ON posts_fts.rowid = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts_fts MATCH ?1
+AND (?2 OR posts.feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = ?3
+))
+ORDER BY rank DESC, posts.published_at DESC
+LIMIT ?4
+`
+
+func (q *Queries) SearchPosts(ctx context.Context, arg database.SearchPostsParams) ([]database.SearchPostsRow, error) {
+	match := ftsQuery(arg.Query)
+	if match == "" {
+		return nil, nil
+	}
+
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		match,
+		arg.AllFeeds,
+		arg.UserID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.SearchPostsRow
+	for rows.Next() {
+		var i database.SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.PublishedAtInferred,
+			&i.FeedName,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateLegacyPostGUID = `
+UPDATE posts
+SET guid = ?1
+WHERE posts.feed_id = ?2
+AND posts.guid = ?3
+AND posts.url = ?3
+AND NOT EXISTS (
+    SELECT 1 FROM posts AS existing
+    WHERE existing.feed_id = ?2
+    AND existing.guid = ?1
+)
+`
+
+func (q *Queries) UpdateLegacyPostGUID(ctx context.Context, arg database.UpdateLegacyPostGUIDParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateLegacyPostGUID, arg.Guid, arg.FeedID, arg.Url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,50 @@
+package sqlitedb
+
+import (
+	"strings"
+
+	"github.com/Breadumi/aggreGator/internal/search"
+)
+
+// ftsQuery translates the web search syntax gator search accepts into an
+// FTS5 query, quoting each phrase. It returns "" when nothing in the query
+// can match.
+func ftsQuery(query string) string {
+	parsed := search.Parse(query, ftsWords)
+	if parsed.Empty() {
+		return ""
+	}
+
+	groups := make([]string, len(parsed.Groups))
+	for i, group := range parsed.Groups {
+		phrases := make([]string, len(group))
+		for j, phrase := range group {
+			phrases[j] = ftsPhrase(phrase)
+		}
+		groups[i] = strings.Join(phrases, " OR ")
+	}
+
+	match := "(" + strings.Join(groups, ") AND (") + ")"
+	for _, phrase := range parsed.Exclude {
+		match += " NOT " + ftsPhrase(phrase)
+	}
+	return match
+}
+
+func ftsPhrase(phrase search.Phrase) string {
+	return `"` + strings.Join(phrase, " ") + `"`
+}
+
+// ftsWords splits s into words the way the unicode61 tokenizer does, so that
+// no quote or operator character reaches the FTS5 query
+func ftsWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !isWordRune(r)
+	})
+}
+
+// isWordRune reports whether r is part of a word for the unicode61
+// tokenizer, which splits on everything else
+func isWordRune(r rune) bool {
+	return r == '_' || r >= 0x80 || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
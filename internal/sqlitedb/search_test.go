@@ -0,0 +1,115 @@
+package sqlitedb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+)
+
+func TestFTSQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"golang", `("golang")`},
+		{"go generics", `("go") AND ("generics")`},
+		{`"go generics" release`, `("go generics") AND ("release")`},
+		{"go -rust", `("go") NOT "rust"`},
+		{`go -"rust lang"`, `("go") NOT "rust lang"`},
+		{"go or rust zig", `("go" OR "rust") AND ("zig")`},
+		{"go OR rust", `("go" OR "rust")`},
+		{"or go", `("go")`},
+		{"C++ e-mail", `("C") AND ("e mail")`},
+		{`"unterminated phrase`, `("unterminated phrase")`},
+		{"café", `("café")`},
+		{"-rust", ""},
+		{"  ", ""},
+		{"+++", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ftsQuery(tt.query); got != tt.want {
+			t.Errorf("ftsQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchPosts(t *testing.T) {
+	ctx := context.Background()
+	q := newTestDB(t)
+	alice := testUser(t, q, "alice")
+	bob := testUser(t, q, "bob")
+	feed := testFeed(t, q, alice, "https://example.com/feed")
+	other := testFeed(t, q, bob, "https://example.com/other")
+	for _, f := range []database.Feed{feed, other} {
+		if _, err := q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+			CreatedAt: testNow,
+			UpdatedAt: testNow,
+			UserID:    f.UserID,
+			FeedID:    f.ID,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testPost(t, q, feed, "description", "Weekly notes", "Some thoughts on generics in Go", testNow)
+	testPost(t, q, feed, "title", "Generics in Go", "A long read", testNow.Add(-time.Hour))
+	testPost(t, q, feed, "rust", "Generics in Rust", "Traits and generics", testNow)
+	testPost(t, q, other, "other", "Go generics explained", "", testNow)
+
+	search := func(query string, allFeeds bool) []string {
+		t.Helper()
+		rows, err := q.SearchPosts(ctx, database.SearchPostsParams{
+			Query:    query,
+			AllFeeds: allFeeds,
+			UserID:   alice.ID,
+			Limit:    10,
+		})
+		if err != nil {
+			t.Fatalf("searching %q: %v", query, err)
+		}
+		var titles []string
+		for _, row := range rows {
+			titles = append(titles, row.Title)
+		}
+		return titles
+	}
+
+	tests := []struct {
+		query    string
+		allFeeds bool
+		want     []string
+	}{
+		// a title match outranks a description match, as with Postgres'
+		// weighted search_vector, and stemming matches "generic"
+		{"generic go", false, []string{"Generics in Go", "Weekly notes"}},
+		{"generics -rust", false, []string{"Generics in Go", "Weekly notes"}},
+		{`"generics in go"`, false, []string{"Generics in Go", "Weekly notes"}},
+		{"traits or thoughts", false, []string{"Generics in Rust", "Weekly notes"}},
+		{"explained", false, nil},
+		{"explained", true, []string{"Go generics explained"}},
+	}
+	for _, tt := range tests {
+		got := search(tt.query, tt.allFeeds)
+		if len(got) != len(tt.want) {
+			t.Errorf("search %q (all feeds %v) = %q, want %q", tt.query, tt.allFeeds, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("search %q (all feeds %v) = %q, want %q", tt.query, tt.allFeeds, got, tt.want)
+				break
+			}
+		}
+	}
+
+	// posts leave the index when they're deleted
+	if _, err := q.DeleteUnstarredPostsBefore(ctx, testNow.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if got := search("generics", true); len(got) != 0 {
+		t.Errorf("search after pruning = %q, want nothing", got)
+	}
+}
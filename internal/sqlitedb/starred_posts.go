@@ -0,0 +1,80 @@
+package sqlitedb
+
+import (
+	"context"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+const getStarredPostsForUser = `
+SELECT ` + postColumns + `, feeds.name AS feed_name, starred_posts.created_at AS starred_at
+FROM starred_posts
+INNER JOIN posts ON starred_posts.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE starred_posts.user_id = ?1
+ORDER BY starred_posts.created_at DESC
+`
+
+func (q *Queries) GetStarredPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetStarredPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getStarredPostsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetStarredPostsForUserRow
+	for rows.Next() {
+		var i database.GetStarredPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.PublishedAtInferred,
+			&i.Guid,
+			&i.Content,
+			&i.Author,
+			&i.FeedName,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const starPost = `
+INSERT INTO starred_posts (user_id, post_id, created_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+func (q *Queries) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	_, err := q.db.ExecContext(ctx, starPost, bind(arg.UserID, arg.PostID, arg.CreatedAt)...)
+	return err
+}
+
+const unstarPost = `
+DELETE FROM starred_posts
+WHERE starred_posts.user_id = ?1
+AND starred_posts.post_id = ?2
+`
+
+func (q *Queries) UnstarPost(ctx context.Context, arg database.UnstarPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unstarPost, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,35 @@
+package sqlitedb
+
+import (
+	"context"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+)
+
+const markFeedRead = `
+INSERT INTO user_post_state (user_id, post_id, read_at)
+SELECT ?1, posts.id, ?2
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feeds.url = ?3
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+func (q *Queries) MarkFeedRead(ctx context.Context, arg database.MarkFeedReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markFeedRead, bind(arg.UserID, arg.ReadAt, arg.Url)...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markPostRead = `
+INSERT INTO user_post_state (user_id, post_id, read_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+func (q *Queries) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostRead, bind(arg.UserID, arg.PostID, arg.ReadAt)...)
+	return err
+}
@@ -0,0 +1,98 @@
+package sqlitedb
+
+import (
+	"context"
+
+	"github.com/Breadumi/aggreGator/internal/database"
+	"github.com/google/uuid"
+)
+
+const createUser = `
+INSERT INTO users (id, created_at, updated_at, name)
+VALUES (?1, ?2, ?3, ?4)
+RETURNING ` + userColumns
+
+func (q *Queries) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	row := q.db.QueryRowContext(ctx, createUser, bind(
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+	)...)
+	return scanUser(row)
+}
+
+// deleteUsers relies on foreign keys cascading from users, like TRUNCATE
+// users CASCADE does in Postgres
+const deleteUsers = `
+DELETE FROM users
+`
+
+func (q *Queries) DeleteUsers(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteUsers)
+	return err
+}
+
+const getUserByAPIKey = `
+SELECT ` + userColumns + ` FROM users
+WHERE api_key = ?1
+`
+
+func (q *Queries) GetUserByAPIKey(ctx context.Context, apiKey string) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUserByAPIKey, apiKey))
+}
+
+const getUserByID = `
+SELECT ` + userColumns + ` FROM users
+WHERE id = ?1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUserByID, id))
+}
+
+const getUserByName = `
+SELECT ` + userColumns + ` FROM users
+WHERE name = ?1
+`
+
+func (q *Queries) GetUserByName(ctx context.Context, name string) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUserByName, name))
+}
+
+const getUsers = `
+SELECT ` + userColumns + ` FROM users
+`
+
+func (q *Queries) GetUsers(ctx context.Context) ([]database.User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.User
+	for rows.Next() {
+		i, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const rotateUserAPIKey = `
+UPDATE users
+SET api_key = lower(hex(randomblob(32))), updated_at = ?2
+WHERE id = ?1
+RETURNING ` + userColumns
+
+func (q *Queries) RotateUserAPIKey(ctx context.Context, arg database.RotateUserAPIKeyParams) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, rotateUserAPIKey, bind(arg.ID, arg.UpdatedAt)...))
+}
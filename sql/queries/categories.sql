@@ -0,0 +1,16 @@
+-- name: CreateCategory :one
+INSERT INTO categories (name)
+VALUES ($1)
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: AddPostCategory :exec
+INSERT INTO post_categories (post_id, category_id)
+VALUES ($1, $2)
+ON CONFLICT (post_id, category_id) DO NOTHING;
+
+-- name: GetCategoriesForPost :many
+SELECT categories.* FROM categories
+INNER JOIN post_categories ON categories.id = post_categories.category_id
+WHERE post_categories.post_id = $1
+ORDER BY categories.name;
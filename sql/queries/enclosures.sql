@@ -0,0 +1,38 @@
+-- name: CreateEnclosure :exec
+INSERT INTO enclosures (post_id, url, mime_type, size_bytes, duration_seconds)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (post_id, url) DO NOTHING;
+
+-- name: GetEnclosureByID :one
+SELECT * FROM enclosures
+WHERE id = $1;
+
+-- name: GetEnclosuresByUser :many
+SELECT
+    enclosures.*,
+    posts.title AS post_title, posts.published_at, feeds.name AS feed_name,
+    enclosure_downloads.path AS download_path, enclosure_downloads.downloaded_at
+FROM enclosures
+INNER JOIN posts ON enclosures.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN enclosure_downloads ON enclosure_downloads.enclosure_id = enclosures.id
+    AND enclosure_downloads.user_id = @user_id
+WHERE posts.feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = @user_id
+)
+AND (sqlc.narg('feed_url')::text IS NULL OR feeds.url = sqlc.narg('feed_url'))
+AND (NOT sqlc.arg('pending_only')::boolean OR enclosure_downloads.enclosure_id IS NULL)
+ORDER BY posts.published_at DESC, enclosures.id
+LIMIT sqlc.arg('limit');
+
+-- name: GetEnclosureDownload :one
+SELECT * FROM enclosure_downloads
+WHERE user_id = $1
+AND enclosure_id = $2;
+
+-- name: MarkEnclosureDownloaded :exec
+INSERT INTO enclosure_downloads (user_id, enclosure_id, path, size_bytes, downloaded_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, enclosure_id) DO UPDATE
+SET path = EXCLUDED.path, size_bytes = EXCLUDED.size_bytes, downloaded_at = EXCLUDED.downloaded_at;
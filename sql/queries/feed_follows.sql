@@ -1,11 +1,12 @@
 -- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (created_at, updated_at, user_id, feed_id)
+    INSERT INTO feed_follows (created_at, updated_at, user_id, feed_id, folder)
     VALUES (
         $1,
         $2,
         $3,
-        $4
+        $4,
+        $5
     )
     RETURNING *
 )
@@ -21,7 +22,16 @@ INNER JOIN feeds ON inserted_feed_follow.feed_id = feeds.id;
 SELECT * FROM feed_follows;
 
 -- name: GetFeedFollowsForUser :many
-SELECT feed_follows.*, users.name AS user_name, feeds.name AS feed_name
+SELECT feed_follows.*, users.name AS user_name, feeds.name AS feed_name, feeds.url AS feed_url,
+    (
+        SELECT COUNT(*) FROM posts
+        WHERE posts.feed_id = feed_follows.feed_id
+        AND NOT EXISTS (
+            SELECT 1 FROM user_post_state
+            WHERE user_post_state.user_id = feed_follows.user_id
+            AND user_post_state.post_id = posts.id
+        )
+    ) AS unread_count
 FROM feed_follows
 INNER JOIN users ON feed_follows.user_id = users.id
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
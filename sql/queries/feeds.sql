@@ -13,16 +13,60 @@ RETURNING *;
 -- name: GetFeeds :many
 SELECT * FROM feeds;
 
+-- name: GetFeedByID :one
+SELECT * FROM feeds
+WHERE id = $1;
+
 -- name: GetFeedByURL :one
 SELECT * FROM feeds
 WHERE url = $1;
 
--- name: MarkFeedFetched :exec
+-- name: ClaimNextFeed :one
+-- A feed asked for by url is claimed whether or not it is due.
 UPDATE feeds
-SET updated_at = $2, last_fetched_at = $2
-WHERE feeds.id = $1;
+SET updated_at = @now, last_fetched_at = @now, claimed_until = @claimed_until
+WHERE feeds.id = (
+    SELECT id FROM feeds
+    WHERE (feeds.claimed_until IS NULL OR feeds.claimed_until < @now)
+    AND (sqlc.narg('url')::text IS NOT NULL OR (
+        (feeds.backoff_until IS NULL OR feeds.backoff_until <= @now)
+        AND (feeds.next_fetch_at IS NULL OR feeds.next_fetch_at <= @now)
+    ))
+    AND (sqlc.narg('url')::text IS NULL OR feeds.url = sqlc.narg('url'))
+    AND (sqlc.narg('user_id')::uuid IS NULL OR feeds.id IN (
+        SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = sqlc.narg('user_id')
+    ))
+    ORDER BY feeds.next_fetch_at ASC NULLS FIRST, feeds.last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
 
--- name: GetNextFeedToFetch :one
-SELECT * FROM feeds
-ORDER BY feeds.last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+-- The updates below run as a worker finishes with a feed it claimed. Each
+-- only applies while the feed's claim is still the one the worker took, so
+-- a worker whose lease ran out can't overwrite the feed's new owner.
+
+-- name: MarkFeedSucceeded :exec
+UPDATE feeds
+SET consecutive_failures = 0, last_error = NULL, last_succeeded_at = $2, backoff_until = NULL
+WHERE feeds.id = $1 AND feeds.claimed_until = $3;
+
+-- name: MarkFeedFailed :exec
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1, last_error = $2, backoff_until = $3
+WHERE feeds.id = $1 AND feeds.claimed_until = $4;
+
+-- name: ReleaseFeedClaim :exec
+UPDATE feeds
+SET claimed_until = NULL
+WHERE feeds.id = $1 AND feeds.claimed_until = $2;
+
+-- name: SetFeedNextFetch :exec
+UPDATE feeds
+SET next_fetch_at = $2
+WHERE feeds.id = $1 AND feeds.claimed_until = $3;
+
+-- name: UpdateFeedCacheHeaders :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3
+WHERE feeds.id = $1;
@@ -1,13 +1,100 @@
 -- name: CreatePost :one
-INSERT INTO posts (created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES($1, $2, $3, $4, $5, $6, $7)
-ON CONFLICT (url) DO NOTHING
+-- Posts deleted by prune aren't saved again while their feed still lists them.
+INSERT INTO posts (created_at, updated_at, title, url, description, published_at, feed_id, published_at_inferred, guid, content, author)
+SELECT
+    @created_at::timestamp, @updated_at::timestamp, @title::text, @url::text, @description::text, @published_at::timestamp,
+    @feed_id::uuid, @published_at_inferred::boolean, @guid::text, @content::text, @author::text
+WHERE NOT EXISTS (
+    SELECT 1 FROM pruned_posts
+    WHERE pruned_posts.feed_id = @feed_id AND pruned_posts.guid = @guid
+)
+ON CONFLICT (feed_id, guid) DO NOTHING
 RETURNING *;
 
--- name: GetPostsByUser :many
+-- name: GetPostByID :one
 SELECT * FROM posts
-WHERE feed_id IN (
+WHERE id = $1;
+
+-- name: GetPostsByUser :many
+SELECT posts.*, feeds.name AS feed_name, feeds.url AS feed_url FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.feed_id IN (
     SELECT feed_id FROM feed_follows
-    WHERE feed_follows.user_id = $1
+    WHERE feed_follows.user_id = @user_id
 )
-LIMIT $2;
\ No newline at end of file
+AND (sqlc.narg('since')::timestamp IS NULL OR posts.published_at >= sqlc.narg('since'))
+AND (sqlc.narg('until')::timestamp IS NULL OR posts.published_at < sqlc.narg('until'))
+AND (sqlc.narg('feed_url')::text IS NULL OR feeds.url = sqlc.narg('feed_url'))
+AND (sqlc.narg('category')::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_categories
+    INNER JOIN categories ON post_categories.category_id = categories.id
+    WHERE post_categories.post_id = posts.id
+    AND lower(categories.name) = lower(sqlc.narg('category'))
+))
+AND (sqlc.narg('author')::text IS NULL OR lower(posts.author) = lower(sqlc.narg('author')))
+AND (NOT sqlc.arg('unread_only')::boolean OR NOT EXISTS (
+    SELECT 1 FROM user_post_state
+    WHERE user_post_state.user_id = @user_id
+    AND user_post_state.post_id = posts.id
+))
+ORDER BY posts.published_at DESC, posts.id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetFeedPostingStats :one
+SELECT
+    COUNT(*) AS post_count,
+    COALESCE(EXTRACT(EPOCH FROM MAX(published_at) - MIN(published_at)), 0)::bigint AS span_seconds
+FROM (
+    SELECT published_at FROM posts
+    WHERE posts.feed_id = $1
+    AND NOT posts.published_at_inferred
+    ORDER BY published_at DESC
+    LIMIT 10
+) AS recent_posts;
+
+-- name: DeleteUnstarredPostsBefore :execrows
+-- Pruned posts leave their guid behind in pruned_posts, so scraping the feed
+-- again doesn't bring them back.
+WITH deleted AS (
+    DELETE FROM posts
+    WHERE posts.published_at < $1
+    AND NOT EXISTS (
+        SELECT 1 FROM starred_posts
+        WHERE starred_posts.post_id = posts.id
+    )
+    RETURNING posts.feed_id, posts.guid
+)
+INSERT INTO pruned_posts (feed_id, guid)
+SELECT feed_id, guid FROM deleted
+ON CONFLICT (feed_id, guid) DO NOTHING;
+
+-- name: SearchPosts :many
+SELECT
+    posts.id, posts.title, posts.url, posts.description, posts.published_at, posts.published_at_inferred,
+    feeds.name AS feed_name,
+    ts_rank(posts.search_vector, websearch_to_tsquery('english', @query)) AS rank
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.search_vector @@ websearch_to_tsquery('english', @query)
+AND (sqlc.arg('all_feeds')::boolean OR posts.feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = @user_id
+))
+ORDER BY rank DESC, posts.published_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: DeleteLegacyGUIDFeed :execrows
+DELETE FROM legacy_guid_feeds
+WHERE feed_id = $1;
+
+-- name: UpdateLegacyPostGUID :execrows
+UPDATE posts
+SET guid = @guid
+WHERE posts.feed_id = @feed_id
+AND posts.guid = @url
+AND posts.url = @url
+AND NOT EXISTS (
+    SELECT 1 FROM posts AS existing
+    WHERE existing.feed_id = @feed_id
+    AND existing.guid = @guid
+);
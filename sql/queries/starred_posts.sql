@@ -0,0 +1,17 @@
+-- name: StarPost :exec
+INSERT INTO starred_posts (user_id, post_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: UnstarPost :execrows
+DELETE FROM starred_posts
+WHERE starred_posts.user_id = $1
+AND starred_posts.post_id = $2;
+
+-- name: GetStarredPostsForUser :many
+SELECT posts.*, feeds.name AS feed_name, starred_posts.created_at AS starred_at
+FROM starred_posts
+INNER JOIN posts ON starred_posts.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE starred_posts.user_id = $1
+ORDER BY starred_posts.created_at DESC;
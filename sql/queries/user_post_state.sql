@@ -0,0 +1,12 @@
+-- name: MarkPostRead :exec
+INSERT INTO user_post_state (user_id, post_id, read_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkFeedRead :execrows
+INSERT INTO user_post_state (user_id, post_id, read_at)
+SELECT @user_id::uuid, posts.id, @read_at::timestamp
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feeds.url = @url
+ON CONFLICT (user_id, post_id) DO NOTHING;
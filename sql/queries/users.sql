@@ -12,6 +12,10 @@ RETURNING *;
 SELECT * FROM users
 WHERE name = $1;
 
+-- name: GetUserByAPIKey :one
+SELECT * FROM users
+WHERE api_key = $1;
+
 -- name: GetUserByID :one
 SELECT * FROM users
 WHERE id = $1;
@@ -20,4 +24,10 @@ WHERE id = $1;
 TRUNCATE users CASCADE;
 
 -- name: GetUsers :many
-SELECT * FROM users;
\ No newline at end of file
+SELECT * FROM users;
+
+-- name: RotateUserAPIKey :one
+UPDATE users
+SET api_key = encode(gen_random_bytes(32), 'hex'), updated_at = $2
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,31 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN guid TEXT;
+
+UPDATE posts SET guid = url;
+
+ALTER TABLE posts
+ALTER COLUMN guid SET NOT NULL,
+DROP CONSTRAINT posts_url_key,
+ADD CONSTRAINT posts_feed_id_guid_key UNIQUE(feed_id, guid);
+
+-- The real guids aren't known until each feed is fetched again, so its next
+-- scrape swaps them in for the urls copied above and removes the feed from here.
+CREATE TABLE legacy_guid_feeds(
+    feed_id UUID PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+INSERT INTO legacy_guid_feeds (feed_id)
+SELECT DISTINCT feed_id FROM posts;
+
+-- +goose Down
+DROP TABLE legacy_guid_feeds;
+
+DELETE FROM posts a
+USING posts b
+WHERE a.url = b.url AND a.id > b.id;
+
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_guid_key,
+DROP COLUMN guid,
+ADD CONSTRAINT posts_url_key UNIQUE(url);
@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE user_post_state(
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    read_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE user_post_state;
@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE starred_posts(
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE starred_posts;
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN content TEXT NOT NULL DEFAULT '',
+ADD COLUMN author TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE categories(
+    id SERIAL PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL
+);
+
+CREATE TABLE post_categories(
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    PRIMARY KEY(post_id, category_id)
+);
+
+CREATE INDEX post_categories_category_id_idx ON post_categories(category_id);
+
+-- +goose Down
+DROP TABLE post_categories;
+DROP TABLE categories;
+
+ALTER TABLE posts
+DROP COLUMN author,
+DROP COLUMN content;
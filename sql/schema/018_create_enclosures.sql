@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE enclosures(
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    mime_type TEXT NOT NULL DEFAULT '',
+    size_bytes BIGINT,
+    duration_seconds INTEGER,
+    UNIQUE(post_id, url)
+);
+
+CREATE TABLE enclosure_downloads(
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    enclosure_id INTEGER NOT NULL REFERENCES enclosures(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    downloaded_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(user_id, enclosure_id)
+);
+
+-- +goose Down
+DROP TABLE enclosure_downloads;
+DROP TABLE enclosures;
@@ -0,0 +1,8 @@
+// Package schema embeds the goose migrations for the gator database, so
+// installed binaries can migrate without the source tree.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
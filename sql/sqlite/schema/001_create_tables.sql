@@ -0,0 +1,74 @@
+-- +goose Up
+CREATE TABLE users(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT UNIQUE NOT NULL,
+    api_key TEXT UNIQUE NOT NULL DEFAULT (lower(hex(randomblob(32))))
+);
+
+CREATE TABLE feeds(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT UNIQUE NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_fetched_at TIMESTAMP,
+    etag TEXT,
+    last_modified TEXT,
+    claimed_until TIMESTAMP,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    last_succeeded_at TIMESTAMP,
+    backoff_until TIMESTAMP,
+    next_fetch_at TIMESTAMP
+);
+
+CREATE INDEX feeds_next_fetch_at_idx ON feeds(next_fetch_at);
+
+CREATE TABLE feed_follows(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    folder TEXT NOT NULL DEFAULT '',
+    UNIQUE(user_id, feed_id)
+);
+
+CREATE TABLE posts(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT NOT NULL,
+    published_at TIMESTAMP NOT NULL,
+    feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    published_at_inferred BOOLEAN NOT NULL DEFAULT FALSE,
+    guid TEXT NOT NULL,
+    UNIQUE(feed_id, guid)
+);
+
+CREATE TABLE user_post_state(
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    read_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(user_id, post_id)
+);
+
+CREATE TABLE starred_posts(
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE starred_posts;
+DROP TABLE user_post_state;
+DROP TABLE posts;
+DROP TABLE feed_follows;
+DROP TABLE feeds;
+DROP TABLE users;
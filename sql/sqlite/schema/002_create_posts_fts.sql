@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE VIRTUAL TABLE posts_fts USING fts5(
+    title,
+    description,
+    content='posts',
+    content_rowid='id',
+    tokenize='porter unicode61'
+);
+
+INSERT INTO posts_fts(posts_fts) VALUES ('rebuild');
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_delete AFTER DELETE ON posts BEGIN
+    INSERT INTO posts_fts(posts_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_update AFTER UPDATE OF title, description ON posts BEGIN
+    INSERT INTO posts_fts(posts_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+    INSERT INTO posts_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER posts_fts_update;
+DROP TRIGGER posts_fts_delete;
+DROP TRIGGER posts_fts_insert;
+DROP TABLE posts_fts;
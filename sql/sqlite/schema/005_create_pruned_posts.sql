@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE pruned_posts(
+    feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    guid TEXT NOT NULL,
+    PRIMARY KEY(feed_id, guid)
+);
+
+-- +goose Down
+DROP TABLE pruned_posts;
@@ -0,0 +1,8 @@
+// Package schema embeds the goose migrations for gator's SQLite database,
+// which mirror the Postgres migrations in sql/schema.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS